@@ -0,0 +1,81 @@
+package acl
+
+import (
+	"context"
+	"server/store"
+	"strings"
+)
+
+const (
+	Owner     = "owner"
+	Moderator = "moderator"
+	Member    = "member"
+	Banned    = "banned"
+)
+
+type Action string
+
+const (
+	CreateThread Action = "create thread"
+	Post         Action = "post"
+	EditOthers   Action = "edit others' posts"
+	Vote         Action = "vote"
+	ManageUsers  Action = "manage users"
+)
+
+// matrix lists what every role may do in a forum. Users without a granted
+// role are members; the forum creator is always an owner.
+var matrix = map[string]map[Action]bool{
+	Owner:     {CreateThread: true, Post: true, EditOthers: true, Vote: true, ManageUsers: true},
+	Moderator: {CreateThread: true, Post: true, EditOthers: true, Vote: true},
+	Member:    {CreateThread: true, Post: true, Vote: true},
+	Banned:    {},
+}
+
+func ValidRole(role string) bool {
+	_, ok := matrix[role]
+	return ok
+}
+
+func Allowed(role string, action Action) bool {
+	return matrix[role][action]
+}
+
+type ACL struct {
+	store store.Store
+}
+
+func New(s store.Store) *ACL {
+	return &ACL{
+		store: s,
+	}
+}
+
+// Role resolves the effective role of nickname in the forum.
+func (a *ACL) Role(ctx context.Context, forum, nickname string) (string, error) {
+	f, err := a.store.GetForum(ctx, forum)
+	if err != nil {
+		return "", err
+	}
+	if strings.EqualFold(f.User, nickname) {
+		return Owner, nil
+	}
+
+	role, err := a.store.ForumRole(ctx, f.Slug, nickname)
+	if err != nil {
+		return "", err
+	}
+	if role == "" {
+		return Member, nil
+	}
+	return role, nil
+}
+
+// Can reports whether nickname may perform the action in the forum.
+func (a *ACL) Can(ctx context.Context, forum, nickname string, action Action) (bool, error) {
+	role, err := a.Role(ctx, forum, nickname)
+	if err != nil {
+		return false, err
+	}
+	return Allowed(role, action), nil
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"server/models"
+	"server/store"
+	"time"
+)
+
+// KeyPrefix tells API keys apart from session tokens in the Authorization header.
+const KeyPrefix = "fk_"
+
+const (
+	ScopeRead     = "read"
+	ScopePost     = "post"
+	ScopeVote     = "vote"
+	ScopeModerate = "moderate"
+	ScopeAdmin    = "admin"
+)
+
+var scopes = map[string]bool{
+	ScopeRead:     true,
+	ScopePost:     true,
+	ScopeVote:     true,
+	ScopeModerate: true,
+	ScopeAdmin:    true,
+}
+
+func ValidScope(scope string) bool {
+	return scopes[scope]
+}
+
+// CreateKey stores a new key for the user and returns it with its plain value.
+func (a *Auth) CreateKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	plain, err := randomToken()
+	if err != nil {
+		return key, err
+	}
+	plain = KeyPrefix + plain
+
+	key.Hash = hashToken(plain)
+	key.Created = time.Now()
+	key.LastUsed, key.Revoked = nil, nil
+
+	key, err = a.store.CreateAPIKey(ctx, key)
+	if err != nil {
+		return key, err
+	}
+
+	key.Key = plain
+	return key, nil
+}
+
+func (a *Auth) authenticateKey(ctx context.Context, plain string) (identity, error) {
+	key, err := a.store.GetAPIKey(ctx, hashToken(plain))
+	if store.IsNotFound(err, store.APIKey) {
+		return identity{}, ErrInvalidToken
+	}
+	if err != nil {
+		return identity{}, err
+	}
+
+	now := time.Now()
+	if key.Revoked != nil || key.Expires != nil && !now.Before(*key.Expires) {
+		return identity{}, ErrInvalidToken
+	}
+
+	if err := a.store.TouchAPIKey(ctx, key.Id, now); err != nil {
+		return identity{}, err
+	}
+
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return identity{nickname: key.Nickname, scopes: scopes}, nil
+}
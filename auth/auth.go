@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"server/config"
+	"server/httputils"
+	"server/models"
+	"server/store"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("auth: invalid nickname or password")
+	ErrInvalidToken       = errors.New("auth: invalid or expired token")
+)
+
+type ctxKey int
+
+const identityKey ctxKey = iota
+
+// identity is who a request acts as. Scopes is nil for login sessions,
+// which may do anything their user may do.
+type identity struct {
+	nickname string
+	scopes   []string
+}
+
+// Store is what Auth needs from the storage backend.
+type Store interface {
+	store.AuthStore
+	store.APIKeyStore
+}
+
+// Auth issues login tokens and resolves the bearer token of every request.
+// When it is disabled requests are anonymous and the handlers trust the
+// nickname given in the body, as before.
+type Auth struct {
+	store   Store
+	enabled bool
+	ttl     time.Duration
+}
+
+func New(s Store, c config.Auth) *Auth {
+	return &Auth{
+		store:   s,
+		enabled: c.Enabled,
+		ttl:     c.TokenTTL,
+	}
+}
+
+func (a *Auth) Enabled() bool {
+	return a.enabled
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// Login checks the password and returns a new token with its plain value.
+func (a *Auth) Login(ctx context.Context, nickname, password string) (models.Token, error) {
+	nickname, hash, err := a.store.PasswordHash(ctx, nickname)
+	if store.IsNotFound(err, store.User) {
+		return models.Token{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return models.Token{}, err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return models.Token{}, ErrInvalidCredentials
+	}
+
+	plain, err := randomToken()
+	if err != nil {
+		return models.Token{}, err
+	}
+
+	now := time.Now()
+	token := models.Token{
+		Token:    hashToken(plain),
+		Nickname: nickname,
+		Created:  now,
+		Expires:  now.Add(a.ttl),
+	}
+	if err := a.store.CreateToken(ctx, token); err != nil {
+		return models.Token{}, err
+	}
+
+	token.Token = plain
+	return token, nil
+}
+
+func (a *Auth) Logout(ctx context.Context, token string) error {
+	return a.store.DeleteToken(ctx, hashToken(token))
+}
+
+// Authenticate resolves a plain bearer token to its nickname.
+func (a *Auth) Authenticate(ctx context.Context, token string) (string, error) {
+	t, err := a.store.GetToken(ctx, hashToken(token))
+	if store.IsNotFound(err, store.Token) {
+		return "", ErrInvalidToken
+	}
+	if err != nil {
+		return "", err
+	}
+	if !time.Now().Before(t.Expires) {
+		return "", ErrInvalidToken
+	}
+	return t.Nickname, nil
+}
+
+// Middleware binds the user of a valid session token or API key to the
+// request context. A missing header leaves the request anonymous; an invalid
+// one is rejected with 401. API keys need the read scope for GET requests.
+func (a *Auth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := BearerToken(r)
+		if !a.enabled || token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var id identity
+		var err error
+		if strings.HasPrefix(token, KeyPrefix) {
+			id, err = a.authenticateKey(r.Context(), token)
+		} else {
+			id.nickname, err = a.Authenticate(r.Context(), token)
+		}
+		if errors.Is(err, ErrInvalidToken) {
+			Unauthorized(w, "Invalid or expired token")
+			return
+		}
+		if err != nil {
+			httputils.Fail(w, r, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), identityKey, id)
+		if r.Method == http.MethodGet && !HasScope(ctx, ScopeRead) {
+			MissingScope(w, ScopeRead)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Nickname returns the authenticated nickname, or "" for anonymous requests.
+func Nickname(ctx context.Context) string {
+	id, _ := ctx.Value(identityKey).(identity)
+	return id.nickname
+}
+
+// HasScope reports whether the request may use the scope. Sessions have
+// every scope and the admin scope includes the others.
+func HasScope(ctx context.Context, scope string) bool {
+	id, ok := ctx.Value(identityKey).(identity)
+	if !ok || id.scopes == nil {
+		return true
+	}
+	for _, s := range id.scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+func BearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+func MissingScope(w http.ResponseWriter, scope string) {
+	httputils.Respond(w, http.StatusForbidden, models.Message{Message: "API key lacks the " + scope + " scope"})
+}
+
+func Unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	httputils.Respond(w, http.StatusUnauthorized, models.Message{Message: message})
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
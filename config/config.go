@@ -0,0 +1,335 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+type Database struct {
+	Host           string        `yaml:"host" toml:"host"`
+	Port           int           `yaml:"port" toml:"port"`
+	User           string        `yaml:"user" toml:"user"`
+	Password       string        `yaml:"password" toml:"password"`
+	Name           string        `yaml:"name" toml:"name"`
+	SSLMode        string        `yaml:"sslmode" toml:"sslmode"`
+	MaxConnections int           `yaml:"max_connections" toml:"max_connections"`
+	AcquireTimeout time.Duration `yaml:"acquire_timeout" toml:"acquire_timeout"`
+	MigrateOnStart bool          `yaml:"migrate_on_start" toml:"migrate_on_start"`
+}
+
+type Server struct {
+	Addr            string        `yaml:"addr" toml:"addr"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// DrainDelay is how long /readyz fails before the listener closes, so
+	// that load balancers stop sending traffic first.
+	DrainDelay     time.Duration `yaml:"drain_delay" toml:"drain_delay"`
+	RequestTimeout time.Duration `yaml:"request_timeout" toml:"request_timeout"`
+	// RouteTimeouts overrides RequestTimeout per mux route template,
+	// e.g. "/api/thread/{slug_or_id}/posts". The user export streams for as
+	// long as the data takes and has no deadline unless one is set here.
+	RouteTimeouts map[string]time.Duration `yaml:"route_timeouts" toml:"route_timeouts"`
+}
+
+type Auth struct {
+	// Enabled requires a bearer token for the endpoints that act on behalf
+	// of a user.
+	Enabled  bool          `yaml:"enabled" toml:"enabled"`
+	TokenTTL time.Duration `yaml:"token_ttl" toml:"token_ttl"`
+}
+
+type Service struct {
+	// AllowClear enables POST /api/service/clear in production mode.
+	AllowClear bool `yaml:"allow_clear" toml:"allow_clear"`
+	// AdminToken is the X-Admin-Token credential for clearing and for the
+	// audit log and statement list, which are refused while it is empty.
+	AdminToken string        `yaml:"admin_token" toml:"admin_token"`
+	ConfirmTTL time.Duration `yaml:"confirm_ttl" toml:"confirm_ttl"`
+}
+
+type Config struct {
+	// Mode is "development" or "production".
+	Mode string `yaml:"mode" toml:"mode"`
+	// Storage selects the backend: "postgres" or "memory". The memory
+	// backend ignores the database section.
+	Storage  string   `yaml:"storage" toml:"storage"`
+	Database Database `yaml:"database" toml:"database"`
+	Server   Server   `yaml:"server" toml:"server"`
+	Auth     Auth     `yaml:"auth" toml:"auth"`
+	Service  Service  `yaml:"service" toml:"service"`
+}
+
+func (c *Config) Production() bool {
+	return c.Mode == "production"
+}
+
+func Default() Config {
+	return Config{
+		Mode:    "development",
+		Storage: "postgres",
+		Database: Database{
+			Host:           "localhost",
+			Port:           5432,
+			User:           "postgres",
+			Name:           "postgres",
+			SSLMode:        "disable",
+			MaxConnections: 100,
+		},
+		Server: Server{
+			Addr:            ":5000",
+			IdleTimeout:     2 * time.Minute,
+			ShutdownTimeout: 30 * time.Second,
+			DrainDelay:      5 * time.Second,
+			RequestTimeout:  30 * time.Second,
+		},
+		Auth: Auth{
+			TokenTTL: 24 * time.Hour,
+		},
+		Service: Service{
+			ConfirmTTL: 5 * time.Minute,
+		},
+	}
+}
+
+// option binds one setting to its command line flag and environment variable.
+type option struct {
+	flag  string
+	env   string
+	usage string
+	value func(c *Config) flag.Value
+}
+
+var options = []option{
+	{"mode", "FORUM_MODE", "development or production", func(c *Config) flag.Value { return (*stringValue)(&c.Mode) }},
+	{"storage", "FORUM_STORAGE", "storage backend: postgres or memory", func(c *Config) flag.Value { return (*stringValue)(&c.Storage) }},
+	{"db-host", "FORUM_DB_HOST", "database host", func(c *Config) flag.Value { return (*stringValue)(&c.Database.Host) }},
+	{"db-port", "FORUM_DB_PORT", "database port", func(c *Config) flag.Value { return (*intValue)(&c.Database.Port) }},
+	{"db-user", "FORUM_DB_USER", "database user", func(c *Config) flag.Value { return (*stringValue)(&c.Database.User) }},
+	{"db-password", "FORUM_DB_PASSWORD", "database password", func(c *Config) flag.Value { return (*stringValue)(&c.Database.Password) }},
+	{"db-name", "FORUM_DB_NAME", "database name", func(c *Config) flag.Value { return (*stringValue)(&c.Database.Name) }},
+	{"db-sslmode", "FORUM_DB_SSLMODE", "database sslmode (disable, allow, prefer, require, verify-ca, verify-full)", func(c *Config) flag.Value { return (*stringValue)(&c.Database.SSLMode) }},
+	{"db-max-connections", "FORUM_DB_MAX_CONNECTIONS", "connection pool size", func(c *Config) flag.Value { return (*intValue)(&c.Database.MaxConnections) }},
+	{"db-acquire-timeout", "FORUM_DB_ACQUIRE_TIMEOUT", "how long to wait for a free pool connection, 0 waits forever", func(c *Config) flag.Value { return (*durationValue)(&c.Database.AcquireTimeout) }},
+	{"migrate-on-start", "FORUM_MIGRATE_ON_START", "apply pending schema migrations before serving", func(c *Config) flag.Value { return (*boolValue)(&c.Database.MigrateOnStart) }},
+	{"addr", "FORUM_ADDR", "HTTP listen address", func(c *Config) flag.Value { return (*stringValue)(&c.Server.Addr) }},
+	{"read-timeout", "FORUM_READ_TIMEOUT", "HTTP read timeout, 0 disables it", func(c *Config) flag.Value { return (*durationValue)(&c.Server.ReadTimeout) }},
+	{"write-timeout", "FORUM_WRITE_TIMEOUT", "HTTP write timeout, 0 disables it", func(c *Config) flag.Value { return (*durationValue)(&c.Server.WriteTimeout) }},
+	{"idle-timeout", "FORUM_IDLE_TIMEOUT", "HTTP keep-alive idle timeout", func(c *Config) flag.Value { return (*durationValue)(&c.Server.IdleTimeout) }},
+	{"request-timeout", "FORUM_REQUEST_TIMEOUT", "default per-request deadline for database work, 0 disables it", func(c *Config) flag.Value { return (*durationValue)(&c.Server.RequestTimeout) }},
+	{"route-timeouts", "FORUM_ROUTE_TIMEOUTS", "per-route deadlines as route=duration pairs separated by commas", func(c *Config) flag.Value { return (*durationMapValue)(&c.Server.RouteTimeouts) }},
+	{"auth-enabled", "FORUM_AUTH_ENABLED", "require bearer tokens for endpoints that act on behalf of a user", func(c *Config) flag.Value { return (*boolValue)(&c.Auth.Enabled) }},
+	{"auth-token-ttl", "FORUM_AUTH_TOKEN_TTL", "lifetime of login tokens", func(c *Config) flag.Value { return (*durationValue)(&c.Auth.TokenTTL) }},
+	{"allow-clear", "FORUM_ALLOW_CLEAR", "allow /api/service/clear in production mode", func(c *Config) flag.Value { return (*boolValue)(&c.Service.AllowClear) }},
+	{"admin-token", "FORUM_ADMIN_TOKEN", "X-Admin-Token credential for the service endpoints", func(c *Config) flag.Value { return (*stringValue)(&c.Service.AdminToken) }},
+	{"clear-confirm-ttl", "FORUM_CLEAR_CONFIRM_TTL", "how long a clear confirmation token stays valid", func(c *Config) flag.Value { return (*durationValue)(&c.Service.ConfirmTTL) }},
+	{"shutdown-timeout", "FORUM_SHUTDOWN_TIMEOUT", "how long to drain in-flight requests on SIGTERM or SIGINT", func(c *Config) flag.Value { return (*durationValue)(&c.Server.ShutdownTimeout) }},
+	{"drain-delay", "FORUM_DRAIN_DELAY", "how long /readyz fails before the listener closes on SIGTERM or SIGINT", func(c *Config) flag.Value { return (*durationValue)(&c.Server.DrainDelay) }},
+}
+
+// defaultRouteTimeouts fill in the routes that no layer configured. They are
+// not part of Default because a YAML file may not repeat a key the map
+// already holds.
+var defaultRouteTimeouts = map[string]time.Duration{
+	"/api/user/{nickname}/export": 0,
+}
+
+// Load builds the configuration from defaults, an optional YAML or TOML file,
+// FORUM_* environment variables and command line flags, each overriding the previous.
+// The arguments left after the flags are returned as the subcommand.
+func Load(args []string) (*Config, []string, error) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+
+	path := fs.String("config", os.Getenv("FORUM_CONFIG"), "path to a YAML or TOML config file")
+
+	scratch := Default()
+	byName := make(map[string]option, len(options))
+	for _, opt := range options {
+		fs.Var(opt.value(&scratch), opt.flag, opt.usage+" (env "+opt.env+")")
+		byName[opt.flag] = opt
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	conf := Default()
+
+	if *path != "" {
+		if err := loadFile(*path, &conf); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, opt := range options {
+		if v, ok := os.LookupEnv(opt.env); ok {
+			if err := opt.value(&conf).Set(v); err != nil {
+				return nil, nil, fmt.Errorf("config: %s: %v", opt.env, err)
+			}
+		}
+	}
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if opt, ok := byName[f.Name]; ok && err == nil {
+			err = opt.value(&conf).Set(f.Value.String())
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for route, d := range defaultRouteTimeouts {
+		if _, ok := conf.Server.RouteTimeouts[route]; !ok {
+			if conf.Server.RouteTimeouts == nil {
+				conf.Server.RouteTimeouts = map[string]time.Duration{}
+			}
+			conf.Server.RouteTimeouts[route] = d
+		}
+	}
+
+	return &conf, fs.Args(), nil
+}
+
+func loadFile(path string, conf *Config) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %v", err)
+	}
+
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, conf)
+	case ".toml":
+		var meta toml.MetaData
+		meta, err = toml.Decode(string(data), conf)
+		if err == nil && len(meta.Undecoded()) != 0 {
+			err = fmt.Errorf("unknown keys %v", meta.Undecoded())
+		}
+	default:
+		return fmt.Errorf("config: %s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("config: %s: %v", path, err)
+	}
+	return nil
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+func (v *stringValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return string(*v)
+}
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*v = intValue(n)
+	return nil
+}
+
+func (v *intValue) String() string {
+	if v == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(*v))
+}
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) String() string {
+	if v == nil {
+		return "false"
+	}
+	return strconv.FormatBool(bool(*v))
+}
+
+func (v *boolValue) IsBoolFlag() bool {
+	return true
+}
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+func (v *durationValue) String() string {
+	if v == nil {
+		return "0s"
+	}
+	return time.Duration(*v).String()
+}
+
+type durationMapValue map[string]time.Duration
+
+func (v *durationMapValue) Set(s string) error {
+	m := map[string]time.Duration{}
+	for k, d := range *v {
+		m[k] = d
+	}
+	for _, item := range strings.Split(s, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		i := strings.LastIndex(item, "=")
+		if i < 0 {
+			return fmt.Errorf("%q is not a route=duration pair", item)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(item[i+1:]))
+		if err != nil {
+			return err
+		}
+		m[strings.TrimSpace(item[:i])] = d
+	}
+	*v = m
+	return nil
+}
+
+func (v *durationMapValue) String() string {
+	if v == nil {
+		return ""
+	}
+	items := make([]string, 0, len(*v))
+	for k, d := range *v {
+		items = append(items, k+"="+d.String())
+	}
+	return strings.Join(items, ",")
+}
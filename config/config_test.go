@@ -0,0 +1,167 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setenv sets the FORUM_* variables for the test and clears every other one,
+// restoring the environment afterwards.
+func setenv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, opt := range append(options, option{env: "FORUM_CONFIG"}) {
+		name := opt.env
+		old, had := os.LookupEnv(name)
+		t.Cleanup(func() {
+			if had {
+				os.Setenv(name, old)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+		os.Unsetenv(name)
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+	}
+}
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	files := []struct {
+		name string
+		data string
+	}{
+		{"config.yaml", "mode: production\nserver:\n  addr: \":1\"\n  request_timeout: 1s\ndatabase:\n  host: file-host\n  port: 1111\n"},
+		{"config.toml", "mode = \"production\"\n[server]\naddr = \":1\"\nrequest_timeout = \"1s\"\n[database]\nhost = \"file-host\"\nport = 1111\n"},
+	}
+
+	for _, f := range files {
+		t.Run(f.name, func(t *testing.T) {
+			setenv(t, map[string]string{
+				"FORUM_DB_PORT": "2222",
+				"FORUM_ADDR":    ":2",
+			})
+			path := writeFile(t, f.name, f.data)
+
+			conf, rest, err := Load([]string{"-config", path, "-addr", ":3", "migrate", "up"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			checks := []struct {
+				layer     string
+				got, want interface{}
+			}{
+				{"default", conf.Storage, "postgres"},
+				{"file", conf.Mode, "production"},
+				{"file", conf.Database.Host, "file-host"},
+				{"file", conf.Server.RequestTimeout, time.Second},
+				{"env", conf.Database.Port, 2222},
+				{"flag", conf.Server.Addr, ":3"},
+			}
+			for _, c := range checks {
+				if c.got != c.want {
+					t.Errorf("%s layer: got %v, want %v", c.layer, c.got, c.want)
+				}
+			}
+			if len(rest) != 2 || rest[0] != "migrate" || rest[1] != "up" {
+				t.Errorf("subcommand: got %v", rest)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	path := writeFile(t, "config.yml", "storage: memory\n")
+	setenv(t, map[string]string{"FORUM_CONFIG": path})
+
+	conf, _, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Storage != "memory" {
+		t.Errorf("storage: got %q, want memory", conf.Storage)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+		env  map[string]string
+		args []string
+	}{
+		{name: "unknown yaml key", file: "config.yaml", data: "nope: 1\n"},
+		{name: "unknown toml key", file: "config.toml", data: "nope = 1\n"},
+		{name: "unsupported format", file: "config.json", data: "{}"},
+		{name: "bad env value", env: map[string]string{"FORUM_DB_PORT": "many"}},
+		{name: "bad flag value", args: []string{"-request-timeout", "soon"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t, tt.env)
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeFile(t, tt.file, tt.data)}, args...)
+			}
+			if _, _, err := Load(args); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestDurationMapValue(t *testing.T) {
+	var m durationMapValue
+	if err := m.Set("/api/a=1s, /api/b={x}=2m"); err != nil {
+		t.Fatal(err)
+	}
+	if m["/api/a"] != time.Second || m["/api/b={x}"] != 2*time.Minute {
+		t.Errorf("got %v", map[string]time.Duration(m))
+	}
+	if err := m.Set("/api/c"); err == nil {
+		t.Error("expected an error for a missing duration")
+	}
+}
+
+func TestRouteTimeoutsKeepExportDefault(t *testing.T) {
+	files := []struct {
+		name string
+		data string
+	}{
+		{"config.yaml", "server:\n  route_timeouts:\n    \"/api/thread/{slug_or_id}/posts\": 1m\n"},
+		{"config.toml", "[server.route_timeouts]\n\"/api/thread/{slug_or_id}/posts\" = \"1m\"\n"},
+		{"export.yaml", "server:\n  route_timeouts:\n    \"/api/thread/{slug_or_id}/posts\": 1m\n    \"/api/user/{nickname}/export\": 0s\n"},
+	}
+
+	for _, f := range files {
+		t.Run(f.name, func(t *testing.T) {
+			setenv(t, nil)
+			conf, _, err := Load([]string{"-config", writeFile(t, f.name, f.data)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			routes := conf.Server.RouteTimeouts
+			if d := routes["/api/thread/{slug_or_id}/posts"]; d != time.Minute {
+				t.Errorf("posts route: got %v, want 1m", d)
+			}
+			if d, ok := routes["/api/user/{nickname}/export"]; !ok || d != 0 {
+				t.Errorf("export route: got %v, %v, want no deadline", d, ok)
+			}
+		})
+	}
+}
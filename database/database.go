@@ -1,27 +1,45 @@
 package database
 
 import (
+	"context"
+	"net"
+	"net/url"
+	"server/config"
+	"strconv"
+
 	"github.com/jackc/pgx"
 	_ "github.com/jackc/pgx/stdlib"
 )
 
 type Postgres struct {
-	conn *pgx.ConnPool
+	conn       *pgx.ConnPool
+	statements *Statements
 }
 
-func NewPostgres() (*Postgres, error) {
-	conf := pgx.ConnConfig{
-		User:                 "postgres",
-		Database:             "postgres",
-		Password:             "admin",
-		PreferSimpleProtocol: false,
+func NewPostgres(c config.Database) (*Postgres, error) {
+	uri := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     c.Name,
+		RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
 	}
 
+	conf, err := pgx.ParseURI(uri.String())
+	if err != nil {
+		return nil, err
+	}
+	conf.PreferSimpleProtocol = false
+
+	statements := NewStatements()
+	conf.Logger = &queryLogger{statements: statements}
+	conf.LogLevel = pgx.LogLevelInfo
+
 	poolConf := pgx.ConnPoolConfig{
 		ConnConfig:     conf,
-		MaxConnections: 100,
+		MaxConnections: c.MaxConnections,
 		AfterConnect:   nil,
-		AcquireTimeout: 0,
+		AcquireTimeout: c.AcquireTimeout,
 	}
 
 	conn, err := pgx.NewConnPool(poolConf)
@@ -29,7 +47,8 @@ func NewPostgres() (*Postgres, error) {
 		return nil, err
 	}
 	return &Postgres{
-		conn: conn,
+		conn:       conn,
+		statements: statements,
 	}, nil
 }
 
@@ -37,6 +56,21 @@ func (p *Postgres) GetPostgres() *pgx.ConnPool {
 	return p.conn
 }
 
+func (p *Postgres) Statements() *Statements {
+	return p.statements
+}
+
+// Ping acquires a pool connection and round-trips to the server.
+func (p *Postgres) Ping(ctx context.Context) error {
+	conn, err := p.conn.AcquireEx(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.conn.Release(conn)
+
+	return conn.Ping(ctx)
+}
+
 func (p *Postgres) Close() error {
 	p.conn.Close()
 	return nil
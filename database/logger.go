@@ -0,0 +1,30 @@
+package database
+
+import (
+	"server/metrics"
+	"time"
+
+	"github.com/jackc/pgx"
+)
+
+// queryLogger turns pgx log events into query metrics. pgx reports the
+// prepared statement name as "sql", so timings are keyed by the names
+// registered in Statements; ad-hoc SQL is counted as "other".
+type queryLogger struct {
+	statements *Statements
+}
+
+func (l *queryLogger) Log(level pgx.LogLevel, msg string, data map[string]interface{}) {
+	switch msg {
+	case "Query", "Exec":
+		name, _ := data["sql"].(string)
+		if !l.statements.Has(name) {
+			name = "other"
+		}
+		d, _ := data["time"].(time.Duration)
+		err, _ := data["err"].(error)
+		metrics.ObserveQuery(name, d, err)
+	case "waiting for available connection":
+		metrics.ObservePoolWait()
+	}
+}
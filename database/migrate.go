@@ -0,0 +1,280 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLock is the pg_advisory_lock key that serializes migrations
+// between instances started at the same time.
+const migrationLock = 7283529
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrations returns the migrations embedded in the binary ordered by version.
+func Migrations() ([]Migration, error) {
+	files, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, file := range files {
+		m := migrationName.FindStringSubmatch(file.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migrate: unexpected file name %q", file.Name())
+		}
+
+		version, _ := strconv.Atoi(m[1])
+		sql, err := migrationFiles.ReadFile(path.Join("migrations", file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		item, ok := byVersion[version]
+		if !ok {
+			item = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = item
+		}
+		if item.Name != m[2] {
+			return nil, fmt.Errorf("migrate: version %d has two names: %s and %s", version, item.Name, m[2])
+		}
+
+		if m[3] == "up" {
+			item.Up = string(sql)
+		} else {
+			item.Down = string(sql)
+		}
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+	for _, item := range byVersion {
+		if item.Up == "" || item.Down == "" {
+			return nil, fmt.Errorf("migrate: version %d needs both up and down files", item.Version)
+		}
+		result = append(result, *item)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
+// LatestVersion is the schema version the binary expects.
+func LatestVersion() (int, error) {
+	migrations, err := Migrations()
+	if err != nil || len(migrations) == 0 {
+		return 0, err
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// Version returns the newest applied migration, 0 on an empty database.
+func (p *Postgres) Version() (int, error) {
+	if err := p.ensureMigrationsTable(); err != nil {
+		return 0, err
+	}
+
+	var version int
+	err := p.conn.QueryRow("SELECT coalesce(max(version), 0) FROM public.schema_migrations").Scan(&version)
+	return version, err
+}
+
+// CurrentVersion is a read-only Version for health checks: it does not create
+// the schema_migrations table and reports 0 when it is missing.
+func (p *Postgres) CurrentVersion(ctx context.Context) (int, error) {
+	var version int
+	err := p.conn.QueryRowEx(ctx, "SELECT coalesce(max(version), 0) FROM public.schema_migrations", nil).Scan(&version)
+	if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == "42P01" {
+		return 0, nil
+	}
+	return version, err
+}
+
+// MigrationStatus lists every known migration and when it was applied.
+func (p *Postgres) MigrationStatus() ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(p.conn)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Migration: m}
+		if at, ok := applied[m.Version]; ok {
+			at := at
+			status.AppliedAt = &at
+		}
+		result = append(result, status)
+	}
+	return result, nil
+}
+
+// MigrateUp applies every pending migration, each in its own transaction.
+func (p *Postgres) MigrateUp() ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	err = p.withMigrationLock(func(conn *pgx.Conn) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			err := runMigration(conn, m.Up,
+				"INSERT INTO public.schema_migrations(version, name) VALUES ($1, $2)", m.Version, m.Name)
+			if err != nil {
+				return fmt.Errorf("migrate: %04d_%s up: %v", m.Version, m.Name, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+
+	return done, err
+}
+
+// MigrateDown rolls back the last steps applied migrations.
+func (p *Postgres) MigrateDown(steps int) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	err = p.withMigrationLock(func(conn *pgx.Conn) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			err := runMigration(conn, m.Down,
+				"DELETE FROM public.schema_migrations WHERE version = $1", m.Version)
+			if err != nil {
+				return fmt.Errorf("migrate: %04d_%s down: %v", m.Version, m.Name, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+
+	return done, err
+}
+
+func runMigration(conn *pgx.Conn, sql string, record string, args ...interface{}) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecEx(context.Background(), sql, &pgx.QueryExOptions{SimpleProtocol: true})
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(record, args...)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (p *Postgres) withMigrationLock(f func(conn *pgx.Conn) error) error {
+	if err := p.ensureMigrationsTable(); err != nil {
+		return err
+	}
+
+	conn, err := p.conn.Acquire()
+	if err != nil {
+		return err
+	}
+	defer p.conn.Release(conn)
+
+	if _, err := conn.Exec("SELECT pg_advisory_lock($1)", migrationLock); err != nil {
+		return err
+	}
+	defer conn.Exec("SELECT pg_advisory_unlock($1)", migrationLock)
+
+	return f(conn)
+}
+
+func (p *Postgres) ensureMigrationsTable() error {
+	_, err := p.conn.Exec(`CREATE TABLE IF NOT EXISTS public.schema_migrations
+(
+    version    BIGINT PRIMARY KEY,
+    name       TEXT                     NOT NULL,
+    applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+)`)
+	return err
+}
+
+type queryer interface {
+	Query(sql string, args ...interface{}) (*pgx.Rows, error)
+}
+
+func appliedMigrations(q queryer) (map[int]time.Time, error) {
+	rows, err := q.Query("SELECT version, applied_at FROM public.schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
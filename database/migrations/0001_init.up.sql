@@ -1,8 +1,6 @@
-ALTER USER postgres WITH ENCRYPTED PASSWORD 'admin';
-DROP SCHEMA IF EXISTS forum CASCADE;
-CREATE SCHEMA forum;
+CREATE SCHEMA IF NOT EXISTS forum;
 
-create extension if not exists citext;
+CREATE EXTENSION IF NOT EXISTS citext;
 
 -- FUNCTIONS
 
@@ -72,7 +70,7 @@ $$ LANGUAGE plpgsql;
 
 --  USER
 
-CREATE UNLOGGED TABLE forum.user
+CREATE UNLOGGED TABLE IF NOT EXISTS forum.user
 (
     nickname citext collate "POSIX" PRIMARY KEY NOT NULL,
     fullname TEXT                               NOT NULL,
@@ -81,11 +79,11 @@ CREATE UNLOGGED TABLE forum.user
 );
 
 CREATE INDEX IF NOT EXISTS user_all ON forum.user (nickname, fullname, about, email);
-create index if not exists nickname on forum.user using hash (nickname);
+CREATE INDEX IF NOT EXISTS nickname ON forum.user USING hash (nickname);
 
 -- FORUM
 
-CREATE UNLOGGED TABLE forum.forum
+CREATE UNLOGGED TABLE IF NOT EXISTS forum.forum
 (
     id      BIGSERIAL PRIMARY KEY,
     title   TEXT          NOT NULL,
@@ -99,7 +97,7 @@ CREATE UNLOGGED TABLE forum.forum
 
 -- THREAD
 
-CREATE UNLOGGED TABLE forum.thread
+CREATE UNLOGGED TABLE IF NOT EXISTS forum.thread
 (
     id      BIGSERIAL PRIMARY KEY,
     title   TEXT                     NOT NULL,
@@ -115,10 +113,10 @@ CREATE UNLOGGED TABLE forum.thread
         REFERENCES forum.forum (slug)
 );
 
-CREATE INDEX IF NOT EXISTS thread_slug_id ON forum.thread using hash (slug);
+CREATE INDEX IF NOT EXISTS thread_slug_id ON forum.thread USING hash (slug);
 CREATE INDEX IF NOT EXISTS thread_created ON forum.thread (created);
-CREATE INDEX IF NOT EXISTS thread_forum ON forum.thread using hash (forum);
-CREATE INDEX IF NOT EXISTS thread_all on forum.thread (forum, slug, created,title, author, message, votes);
+CREATE INDEX IF NOT EXISTS thread_forum ON forum.thread USING hash (forum);
+CREATE INDEX IF NOT EXISTS thread_all ON forum.thread (forum, slug, created, title, author, message, votes);
 
 DROP TRIGGER IF EXISTS forum_thread ON forum.thread;
 CREATE TRIGGER forum_thread
@@ -129,7 +127,7 @@ EXECUTE PROCEDURE forum.forum_threads_inc();
 
 -- POST
 
-CREATE UNLOGGED TABLE forum.post
+CREATE UNLOGGED TABLE IF NOT EXISTS forum.post
 (
     id       BIGSERIAL PRIMARY KEY,
     parent   BIGINT                   NOT NULL,
@@ -148,9 +146,9 @@ CREATE UNLOGGED TABLE forum.post
         REFERENCES forum.thread (id)
 );
 
-create index if not exists post_thread_parent on forum.post (thread, parent);
-create index if not exists post_pathOne_id_parent on forum.post ((path[1]), id);
-create index post_path on forum.post using gin (path);
+CREATE INDEX IF NOT EXISTS post_thread_parent ON forum.post (thread, parent);
+CREATE INDEX IF NOT EXISTS post_pathOne_id_parent ON forum.post ((path[1]), id);
+CREATE INDEX IF NOT EXISTS post_path ON forum.post USING gin (path);
 
 DROP TRIGGER IF EXISTS forum_post ON forum.post;
 CREATE TRIGGER forum_post
@@ -161,11 +159,11 @@ EXECUTE PROCEDURE forum.forum_posts_inc();
 
 -- VOTE
 
-CREATE UNLOGGED TABLE forum.vote
+CREATE UNLOGGED TABLE IF NOT EXISTS forum.vote
 (
-    thread   bigint    NOT NULL,
-    nickname citext    NOT NULL,
-    voice    BIGINT    NOT NULL,
+    thread   bigint NOT NULL,
+    nickname citext NOT NULL,
+    voice    BIGINT NOT NULL,
     FOREIGN KEY (thread)
         REFERENCES forum.thread (id),
     FOREIGN KEY (nickname)
@@ -173,7 +171,7 @@ CREATE UNLOGGED TABLE forum.vote
     PRIMARY KEY (thread, nickname)
 );
 
-CREATE INDEX IF NOT EXISTS vote_full on forum.vote (thread, nickname, voice);
+CREATE INDEX IF NOT EXISTS vote_full ON forum.vote (thread, nickname, voice);
 
 DROP TRIGGER IF EXISTS forum_vote ON forum.vote;
 CREATE TRIGGER forum_vote
@@ -191,7 +189,7 @@ EXECUTE PROCEDURE forum.thread_votes_inc_2();
 
 -- FORUM USERS
 
-CREATE UNLOGGED TABLE forum.forum_users
+CREATE UNLOGGED TABLE IF NOT EXISTS forum.forum_users
 (
     forum    citext                 NOT NULL,
     nickname citext collate "POSIX" NOT NULL,
@@ -205,4 +203,4 @@ CREATE UNLOGGED TABLE forum.forum_users
     PRIMARY KEY (nickname, forum)
 );
 
-CREATE INDEX forum_users_all on forum.forum_users (forum, nickname, fullname, about, email)
+CREATE INDEX IF NOT EXISTS forum_users_all ON forum.forum_users (forum, nickname, fullname, about, email);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS forum.token;
+DROP TABLE IF EXISTS forum.credentials;
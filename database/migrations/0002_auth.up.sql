@@ -0,0 +1,25 @@
+-- CREDENTIALS
+
+CREATE UNLOGGED TABLE IF NOT EXISTS forum.credentials
+(
+    nickname      citext collate "POSIX" PRIMARY KEY NOT NULL,
+    password_hash TEXT                               NOT NULL,
+    FOREIGN KEY (nickname)
+        REFERENCES forum.user (nickname)
+        ON DELETE CASCADE
+);
+
+-- TOKEN
+
+CREATE UNLOGGED TABLE IF NOT EXISTS forum.token
+(
+    hash     TEXT PRIMARY KEY         NOT NULL,
+    nickname citext                   NOT NULL,
+    created  TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires  TIMESTAMP WITH TIME ZONE NOT NULL,
+    FOREIGN KEY (nickname)
+        REFERENCES forum.user (nickname)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS token_nickname ON forum.token (nickname);
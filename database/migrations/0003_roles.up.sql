@@ -0,0 +1,16 @@
+-- FORUM ROLES
+
+CREATE UNLOGGED TABLE IF NOT EXISTS forum.forum_role
+(
+    forum    citext                 NOT NULL,
+    nickname citext collate "POSIX" NOT NULL,
+    role     TEXT                   NOT NULL,
+    CHECK (role IN ('owner', 'moderator', 'member', 'banned')),
+    FOREIGN KEY (forum)
+        REFERENCES forum.forum (slug)
+        ON DELETE CASCADE,
+    FOREIGN KEY (nickname)
+        REFERENCES forum.user (nickname)
+        ON DELETE CASCADE,
+    PRIMARY KEY (forum, nickname)
+);
@@ -0,0 +1,19 @@
+-- API KEY
+
+CREATE UNLOGGED TABLE IF NOT EXISTS forum.api_key
+(
+    id        BIGSERIAL PRIMARY KEY,
+    hash      TEXT UNIQUE              NOT NULL,
+    nickname  citext                   NOT NULL,
+    name      TEXT                     NOT NULL,
+    scopes    TEXT[]                   NOT NULL,
+    created   TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires   TIMESTAMP WITH TIME ZONE,
+    last_used TIMESTAMP WITH TIME ZONE,
+    revoked   TIMESTAMP WITH TIME ZONE,
+    FOREIGN KEY (nickname)
+        REFERENCES forum.user (nickname)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS api_key_nickname ON forum.api_key (nickname);
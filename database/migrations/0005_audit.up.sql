@@ -0,0 +1,15 @@
+-- AUDIT LOG
+
+-- Logged on purpose and left out of /api/service/clear: the trail has to
+-- outlive the data it describes.
+CREATE TABLE IF NOT EXISTS forum.audit_log
+(
+    id         BIGSERIAL PRIMARY KEY,
+    created    TIMESTAMP WITH TIME ZONE NOT NULL,
+    action     TEXT                     NOT NULL,
+    scope      TEXT                     NOT NULL,
+    actor      TEXT                     NOT NULL,
+    request_id TEXT                     NOT NULL,
+    remote     TEXT                     NOT NULL,
+    result     TEXT                     NOT NULL
+);
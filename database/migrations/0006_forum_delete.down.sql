@@ -0,0 +1 @@
+ALTER TABLE forum.forum DROP COLUMN IF EXISTS deleted;
@@ -0,0 +1,2 @@
+-- Soft-deleted forums keep their rows and counters but are hidden from the API.
+ALTER TABLE forum.forum ADD COLUMN IF NOT EXISTS deleted TIMESTAMP WITH TIME ZONE;
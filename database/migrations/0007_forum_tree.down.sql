@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS forum.forum_parent;
+
+ALTER TABLE forum.forum DROP COLUMN IF EXISTS position;
+ALTER TABLE forum.forum DROP COLUMN IF EXISTS category;
+ALTER TABLE forum.forum DROP COLUMN IF EXISTS parent;
+
+DROP TABLE IF EXISTS forum.category;
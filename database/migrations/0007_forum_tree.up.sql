@@ -0,0 +1,15 @@
+-- Categories group the top-level forums; sub-forums point at their parent
+-- and inherit its category. position orders siblings.
+CREATE UNLOGGED TABLE IF NOT EXISTS forum.category
+(
+    id       BIGSERIAL PRIMARY KEY,
+    slug     citext UNIQUE NOT NULL,
+    title    TEXT          NOT NULL,
+    position INT           NOT NULL DEFAULT 0
+);
+
+ALTER TABLE forum.forum ADD COLUMN IF NOT EXISTS parent citext REFERENCES forum.forum (slug);
+ALTER TABLE forum.forum ADD COLUMN IF NOT EXISTS category citext REFERENCES forum.category (slug);
+ALTER TABLE forum.forum ADD COLUMN IF NOT EXISTS position INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS forum_parent ON forum.forum (parent);
@@ -0,0 +1 @@
+ALTER TABLE forum.thread DROP COLUMN IF EXISTS deleted;
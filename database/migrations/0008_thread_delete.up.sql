@@ -0,0 +1,3 @@
+-- Soft-deleted threads keep their posts and votes but are hidden from the API
+-- and left out of the forum counters.
+ALTER TABLE forum.thread ADD COLUMN IF NOT EXISTS deleted TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1 @@
+ALTER TABLE forum.post DROP COLUMN IF EXISTS deleted;
@@ -0,0 +1,3 @@
+-- A deleted post stays in the tree as a tombstone so that its replies keep
+-- their path; the message is wiped and the author is hidden by the API.
+ALTER TABLE forum.post ADD COLUMN IF NOT EXISTS deleted BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,12 @@
+-- Every change of a post message keeps the text it replaced. The editor is
+-- NULL when the API runs without authentication.
+CREATE UNLOGGED TABLE IF NOT EXISTS forum.post_revision
+(
+    id      BIGSERIAL PRIMARY KEY,
+    post    BIGINT                   NOT NULL REFERENCES forum.post (id) ON DELETE CASCADE,
+    editor  citext REFERENCES forum.user (nickname) ON DELETE SET NULL,
+    message TEXT                     NOT NULL,
+    created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS post_revision_post ON forum.post_revision (post, id);
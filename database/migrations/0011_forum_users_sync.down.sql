@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS forum_users_sync ON forum.user;
+DROP FUNCTION IF EXISTS forum.forum_users_sync();
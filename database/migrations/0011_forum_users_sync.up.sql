@@ -0,0 +1,34 @@
+-- forum_users keeps a copy of the profile for index-only scans of the forum
+-- users; refresh the copies whenever the profile changes.
+CREATE OR REPLACE FUNCTION forum.forum_users_sync()
+    RETURNS TRIGGER AS
+$$
+BEGIN
+    UPDATE forum.forum_users
+    SET fullname = NEW.fullname,
+        about    = NEW.about,
+        email    = NEW.email
+    WHERE nickname = NEW.nickname;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS forum_users_sync ON forum.user;
+CREATE TRIGGER forum_users_sync
+    AFTER UPDATE OF fullname, about, email
+    ON forum.user
+    FOR EACH ROW
+    WHEN (OLD.fullname IS DISTINCT FROM NEW.fullname
+        OR OLD.about IS DISTINCT FROM NEW.about
+        OR OLD.email IS DISTINCT FROM NEW.email)
+EXECUTE PROCEDURE forum.forum_users_sync();
+
+-- Bring the copies that went stale before the trigger existed up to date.
+UPDATE forum.forum_users fu
+SET fullname = u.fullname,
+    about    = u.about,
+    email    = u.email
+FROM forum.user u
+WHERE u.nickname = fu.nickname
+  AND (fu.fullname, fu.about, fu.email) IS DISTINCT FROM (u.fullname, u.about, u.email);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS forum.user_fullname_trgm;
+DROP INDEX IF EXISTS forum.user_nickname_trgm;
@@ -0,0 +1,6 @@
+-- Trigram indexes for the user search. Matching Cyrillic needs a database
+-- whose LC_CTYPE classifies it as letters, e.g. a UTF-8 locale rather than C.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS user_nickname_trgm ON forum.user USING gin (lower(nickname::text) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS user_fullname_trgm ON forum.user USING gin (lower(fullname) gin_trgm_ops);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS forum.post_author_created;
+DROP INDEX IF EXISTS forum.thread_author_created;
@@ -0,0 +1,4 @@
+-- Author indexes for the per-user thread and post listings, which would
+-- otherwise scan every thread and post.
+CREATE INDEX IF NOT EXISTS thread_author_created ON forum.thread (author, created, id);
+CREATE INDEX IF NOT EXISTS post_author_created ON forum.post (author, created, id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS forum.user_nickname_trgm;
+CREATE INDEX IF NOT EXISTS user_nickname_trgm ON forum.user USING gin (lower(nickname::text) gin_trgm_ops);
@@ -0,0 +1,5 @@
+-- The nickname column is collated "POSIX", whose lower() leaves non-ASCII
+-- letters alone, so the search lower-cases it in the default collation and
+-- the trigram index has to match that expression.
+DROP INDEX IF EXISTS forum.user_nickname_trgm;
+CREATE INDEX IF NOT EXISTS user_nickname_trgm ON forum.user USING gin (lower(nickname::text COLLATE "default") gin_trgm_ops);
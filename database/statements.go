@@ -0,0 +1,149 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx"
+)
+
+type Statement struct {
+	Name string `json:"name"`
+	SQL  string `json:"sql"`
+}
+
+type StatementStatus struct {
+	Statement
+	Prepared bool   `json:"prepared"`
+	Error    string `json:"error,omitempty"`
+}
+
+type StatementError struct {
+	Name string
+	Err  error
+}
+
+// PrepareError lists every statement the database refused to prepare.
+type PrepareError struct {
+	Failed []StatementError
+	Total  int
+}
+
+func (e *PrepareError) Error() string {
+	items := make([]string, 0, len(e.Failed))
+	for _, f := range e.Failed {
+		items = append(items, f.Name+": "+f.Err.Error())
+	}
+	return fmt.Sprintf("database: %d of %d statements failed to prepare: %s",
+		len(e.Failed), e.Total, strings.Join(items, "; "))
+}
+
+// Statements is the registry of named prepared statements used by the handlers.
+type Statements struct {
+	mu       sync.RWMutex
+	list     []Statement
+	index    map[string]int
+	errors   map[string]error
+	prepared bool
+}
+
+func NewStatements() *Statements {
+	return &Statements{
+		index:  map[string]int{},
+		errors: map[string]error{},
+	}
+}
+
+// Add registers a statement. Registering the same name twice is a programming
+// error and panics.
+func (s *Statements) Add(name, sql string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.index[name]; ok {
+		panic("database: statement " + name + " registered twice")
+	}
+	s.index[name] = len(s.list)
+	s.list = append(s.list, Statement{Name: name, SQL: sql})
+}
+
+// Prepare prepares every registered statement on the pool and returns
+// a *PrepareError naming the ones that failed.
+func (s *Statements) Prepare(conn *pgx.ConnPool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var failed []StatementError
+	s.errors = map[string]error{}
+	for _, st := range s.list {
+		if _, err := conn.Prepare(st.Name, st.SQL); err != nil {
+			s.errors[st.Name] = err
+			failed = append(failed, StatementError{Name: st.Name, Err: err})
+		}
+	}
+	s.prepared = true
+
+	if failed != nil {
+		return &PrepareError{Failed: failed, Total: len(s.list)}
+	}
+	return nil
+}
+
+// Ready reports whether Prepare ran and every statement was accepted.
+func (s *Statements) Ready() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.prepared && len(s.errors) == 0
+}
+
+func (s *Statements) Has(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.index[name]
+	return ok
+}
+
+func (s *Statements) List() []StatementStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]StatementStatus, 0, len(s.list))
+	for _, st := range s.list {
+		status := StatementStatus{Statement: st, Prepared: s.prepared}
+		if err, ok := s.errors[st.Name]; ok {
+			status.Prepared = false
+			status.Error = err.Error()
+		}
+		result = append(result, status)
+	}
+	return result
+}
+
+// QueryError tags a database error with the statement that produced it.
+type QueryError struct {
+	Statement string
+	Err       error
+}
+
+func (e *QueryError) Error() string {
+	return e.Statement + ": " + e.Err.Error()
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+func (e *QueryError) StatementName() string {
+	return e.Statement
+}
+
+// Wrap returns nil for a nil err and a *QueryError otherwise.
+func Wrap(statement string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &QueryError{Statement: statement, Err: err}
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx"
+)
+
+// The pool's own Query, Exec and Begin ignore the context while waiting for
+// a connection, so these helpers acquire with AcquireEx first and run every
+// statement with the request context.
+
+func (p *Postgres) acquire(ctx context.Context) (*pgx.Conn, error) {
+	return p.conn.AcquireEx(ctx)
+}
+
+func (p *Postgres) Exec(ctx context.Context, sql string, args ...interface{}) (pgx.CommandTag, error) {
+	conn, err := p.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer p.conn.Release(conn)
+
+	return conn.ExecEx(ctx, sql, nil, args...)
+}
+
+func (p *Postgres) Query(ctx context.Context, sql string, args ...interface{}) (*Rows, error) {
+	conn, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryEx(ctx, sql, nil, args...)
+	if err != nil {
+		p.conn.Release(conn)
+		return nil, err
+	}
+
+	return &Rows{Rows: rows, release: func() { p.conn.Release(conn) }}, nil
+}
+
+func (p *Postgres) QueryRow(ctx context.Context, sql string, args ...interface{}) *Row {
+	return &Row{ctx: ctx, p: p, sql: sql, args: args}
+}
+
+// Begin acquires a connection and starts a transaction bound to ctx.
+func (p *Postgres) Begin(ctx context.Context) (*Tx, error) {
+	return p.BeginTx(ctx, nil)
+}
+
+// BeginTx is Begin with the isolation level and access mode of opts.
+func (p *Postgres) BeginTx(ctx context.Context, opts *pgx.TxOptions) (*Tx, error) {
+	conn, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := conn.BeginEx(ctx, opts)
+	if err != nil {
+		p.conn.Release(conn)
+		return nil, err
+	}
+
+	return &Tx{Tx: tx, ctx: ctx, conn: conn, pool: p.conn}, nil
+}
+
+type Rows struct {
+	*pgx.Rows
+	release func()
+}
+
+func (r *Rows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.Close()
+	return false
+}
+
+func (r *Rows) Close() {
+	r.Rows.Close()
+	if r.release != nil {
+		r.release()
+		r.release = nil
+	}
+}
+
+type Row struct {
+	ctx  context.Context
+	p    *Postgres
+	sql  string
+	args []interface{}
+}
+
+func (r *Row) Scan(dest ...interface{}) error {
+	conn, err := r.p.acquire(r.ctx)
+	if err != nil {
+		return err
+	}
+	defer r.p.conn.Release(conn)
+
+	return conn.QueryRowEx(r.ctx, r.sql, nil, r.args...).Scan(dest...)
+}
+
+// Tx runs every statement with the context it was started with and returns
+// the connection to the pool on Commit or Rollback.
+type Tx struct {
+	*pgx.Tx
+	ctx  context.Context
+	conn *pgx.Conn
+	pool *pgx.ConnPool
+	rows []*pgx.Rows
+}
+
+func (t *Tx) Exec(sql string, args ...interface{}) (pgx.CommandTag, error) {
+	return t.Tx.ExecEx(t.ctx, sql, nil, args...)
+}
+
+func (t *Tx) Query(sql string, args ...interface{}) (*pgx.Rows, error) {
+	rows, err := t.Tx.QueryEx(t.ctx, sql, nil, args...)
+	if rows != nil {
+		t.rows = append(t.rows, rows)
+	}
+	return rows, err
+}
+
+func (t *Tx) QueryRow(sql string, args ...interface{}) *pgx.Row {
+	rows, _ := t.Query(sql, args...)
+	return (*pgx.Row)(rows)
+}
+
+func (t *Tx) Commit() error {
+	t.closeRows()
+	err := t.Tx.CommitEx(t.ctx)
+	t.release()
+	return err
+}
+
+// Rollback does not use the request context: a cancelled request still has
+// to end its transaction before the connection goes back to the pool.
+func (t *Tx) Rollback() error {
+	t.closeRows()
+	err := t.Tx.RollbackEx(context.Background())
+	t.release()
+	return err
+}
+
+func (t *Tx) closeRows() {
+	for _, rows := range t.rows {
+		rows.Close()
+	}
+	t.rows = nil
+}
+
+func (t *Tx) release() {
+	if t.conn != nil {
+		t.pool.Release(t.conn)
+		t.conn = nil
+	}
+}
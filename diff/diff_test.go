@@ -0,0 +1,32 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []string
+	}{
+		{"both empty", "", "", []string{}},
+		{"from empty", "", "one\ntwo", []string{"+ one", "+ two"}},
+		{"to empty", "one\ntwo", "", []string{"- one", "- two"}},
+		{"identical", "one\ntwo", "one\ntwo", []string{"  one", "  two"}},
+		{"insert", "one\nthree", "one\ntwo\nthree", []string{"  one", "+ two", "  three"}},
+		{"delete", "one\ntwo\nthree", "one\nthree", []string{"  one", "- two", "  three"}},
+		{"replace", "one\ntwo", "one\n2", []string{"  one", "- two", "+ 2"}},
+		{"trailing newline added", "one\ntwo", "one\ntwo\n", []string{"  one", "  two"}},
+		{"line after trailing newline", "one\n", "one\ntwo\n", []string{"  one", "+ two"}},
+		{"blank line kept", "one\n\ntwo", "one\n\n2", []string{"  one", "  ", "- two", "+ 2"}},
+		{"crlf", "one\r\ntwo\r\n", "one\ntwo\nthree", []string{"  one", "  two", "+ three"}},
+	}
+
+	for _, tt := range tests {
+		if got := Lines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Lines(%q, %q) = %q, want %q", tt.name, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
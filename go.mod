@@ -3,6 +3,7 @@ module server
 go 1.16
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/cockroachdb/apd v1.1.0 // indirect
 	github.com/go-openapi/strfmt v0.20.1
 	github.com/gofrs/uuid v4.0.0+incompatible // indirect
@@ -10,8 +11,10 @@ require (
 	github.com/jackc/fake v0.0.0-20150926172116-812a484cc733 // indirect
 	github.com/jackc/pgx v3.6.2+incompatible
 	github.com/jmoiron/sqlx v1.3.4
+	github.com/prometheus/client_golang v1.11.1
 	github.com/shopspring/decimal v1.2.0 // indirect
 	github.com/stretchr/objx v0.2.0 // indirect
-	golang.org/x/crypto v0.0.0-20210513164829-c07d793c2f9a // indirect
+	golang.org/x/crypto v0.0.0-20210513164829-c07d793c2f9a
 	golang.org/x/text v0.3.6 // indirect
+	gopkg.in/yaml.v2 v2.4.0
 )
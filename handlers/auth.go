@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"server/auth"
+	"server/httputils"
+	"server/models"
+	"strings"
+)
+
+func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
+	var credentials models.Credentials
+
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	token, err := h.auth.Login(r.Context(), credentials.Nickname, credentials.Password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		auth.Unauthorized(w, "Invalid nickname or password")
+		return
+	}
+	if err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	httputils.Respond(w, http.StatusOK, token)
+}
+
+func (h *Handlers) Logout(w http.ResponseWriter, r *http.Request) {
+	token := auth.BearerToken(r)
+	if token == "" {
+		auth.Unauthorized(w, "Authentication required")
+		return
+	}
+
+	if err := h.auth.Logout(r.Context(), token); err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	httputils.Respond(w, http.StatusOK, nil)
+}
+
+// actAs reports whether the request may act on behalf of nickname with the
+// given API key scope and responds with 401 or 403 when it may not.
+func (h *Handlers) actAs(w http.ResponseWriter, r *http.Request, nickname, scope string) bool {
+	if !h.auth.Enabled() {
+		return true
+	}
+
+	who := auth.Nickname(r.Context())
+	if who == "" {
+		auth.Unauthorized(w, "Authentication required")
+		return false
+	}
+	if !strings.EqualFold(who, nickname) {
+		mes := models.Message{}
+		mes.Message = "Can't act on behalf of user: " + nickname
+		httputils.Respond(w, http.StatusForbidden, mes)
+		return false
+	}
+	if !auth.HasScope(r.Context(), scope) {
+		auth.MissingScope(w, scope)
+		return false
+	}
+	return true
+}
+
+// actAsOwner is actAs for the irreversible and personal-data endpoints:
+// without authentication nobody is known to own the account, so it responds
+// with 403 while authentication is disabled.
+func (h *Handlers) actAsOwner(w http.ResponseWriter, r *http.Request, nickname, scope string) bool {
+	if !h.auth.Enabled() {
+		forbidden(w, "Can't act on behalf of user while authentication is disabled: "+nickname)
+		return false
+	}
+	return h.actAs(w, r, nickname, scope)
+}
+
+// bindNickname fills an empty author or voter with the authenticated user.
+func bindNickname(r *http.Request, nickname *string) {
+	if who := auth.Nickname(r.Context()); who != "" && *nickname == "" {
+		*nickname = who
+	}
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"server/acl"
+	"server/auth"
+	"server/models"
+	"strconv"
+	"testing"
+)
+
+// key returns a new API key of the user with the given scopes.
+func (ts *testServer) key(nickname string, scopes ...string) string {
+	ts.t.Helper()
+	key, err := ts.auth.CreateKey(context.Background(), models.APIKey{Nickname: nickname, Name: "bot", Scopes: scopes})
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	return key.Key
+}
+
+// role grants the user a role in the forum.
+func (ts *testServer) role(forum, nickname, role string) {
+	ts.t.Helper()
+	if _, err := ts.store.SetForumRole(context.Background(), models.ForumRole{Forum: forum, Nickname: nickname, Role: role}); err != nil {
+		ts.t.Fatal(err)
+	}
+}
+
+func TestActAs(t *testing.T) {
+	ts := newTestServer(t, true)
+	ts.user("alice")
+	ts.user("bob")
+	alice, bob := ts.login("alice"), ts.login("bob")
+	readOnly := ts.key("alice", auth.ScopeRead)
+	poster := ts.key("alice", auth.ScopePost)
+
+	tests := []struct {
+		name  string
+		token string
+		user  string
+		want  int
+	}{
+		{"anonymous", "", "alice", http.StatusUnauthorized},
+		{"other user", bob, "alice", http.StatusForbidden},
+		{"other user by case", bob, "ALICE", http.StatusForbidden},
+		{"missing scope", readOnly, "alice", http.StatusForbidden},
+		{"session", alice, "alice", http.StatusCreated},
+		{"key with scope", poster, "Alice", http.StatusCreated},
+		{"bound to the caller", bob, "", http.StatusCreated},
+	}
+	for i, tt := range tests {
+		forum := models.Forum{Slug: "forum-" + strconv.Itoa(i), Title: "Forum", User: tt.user}
+		w := ts.do(ts.h.CreateForum, http.MethodPost, tt.token, nil, forum)
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.want, w.Body)
+		}
+	}
+
+	created, err := ts.store.GetForum(context.Background(), "forum-6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.User != "bob" {
+		t.Errorf("bound forum owner: got %q, want bob", created.User)
+	}
+}
+
+func TestActAsDisabled(t *testing.T) {
+	ts := newTestServer(t, false)
+	ts.user("alice")
+
+	// Without authentication anyone may act on behalf of anyone...
+	forum := models.Forum{Slug: "pirates", Title: "Pirates", User: "alice"}
+	if w := ts.do(ts.h.CreateForum, http.MethodPost, "", nil, forum); w.Code != http.StatusCreated {
+		t.Errorf("create forum: status %d, want 201: %s", w.Code, w.Body)
+	}
+	vars := map[string]string{"nickname": "alice"}
+	if w := ts.do(ts.h.ChangeUser, http.MethodPost, "", vars, models.User{About: "sailor"}); w.Code != http.StatusOK {
+		t.Errorf("change user: status %d, want 200: %s", w.Code, w.Body)
+	}
+
+	// ...but nobody owns the account for the export and deletion.
+	if w := ts.do(ts.h.ExportUser, http.MethodGet, "", vars, nil); w.Code != http.StatusForbidden {
+		t.Errorf("export: status %d, want 403: %s", w.Code, w.Body)
+	}
+	if w := ts.do(ts.h.DeleteUser, http.MethodDelete, "", vars, nil); w.Code != http.StatusForbidden {
+		t.Errorf("delete: status %d, want 403: %s", w.Code, w.Body)
+	}
+}
+
+func TestActAsOwner(t *testing.T) {
+	ts := newTestServer(t, true)
+	ts.user("alice")
+	ts.user("bob")
+	vars := map[string]string{"nickname": "alice"}
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"other user", ts.login("bob"), http.StatusForbidden},
+		{"missing scope", ts.key("alice", auth.ScopePost), http.StatusForbidden},
+		{"key with scope", ts.key("alice", auth.ScopeRead), http.StatusOK},
+		{"session", ts.login("alice"), http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := ts.do(ts.h.ExportUser, http.MethodGet, tt.token, vars, nil)
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.want, w.Body)
+		}
+	}
+}
+
+func TestChangeUserNeedsAdminScope(t *testing.T) {
+	ts := newTestServer(t, true)
+	ts.user("alice")
+	ts.user("bob")
+	vars := map[string]string{"nickname": "alice"}
+	body := models.User{About: "changed"}
+
+	if w := ts.do(ts.h.ChangeUser, http.MethodPost, ts.login("bob"), vars, body); w.Code != http.StatusForbidden {
+		t.Errorf("other user: status %d, want 403: %s", w.Code, w.Body)
+	}
+	if w := ts.do(ts.h.ChangeUser, http.MethodPost, ts.key("alice", auth.ScopeRead, auth.ScopePost), vars, body); w.Code != http.StatusForbidden {
+		t.Errorf("key without admin: status %d, want 403: %s", w.Code, w.Body)
+	}
+	if w := ts.do(ts.h.ChangeUser, http.MethodPost, ts.key("alice", auth.ScopeAdmin), vars, body); w.Code != http.StatusOK {
+		t.Errorf("admin key: status %d, want 200: %s", w.Code, w.Body)
+	}
+}
+
+func TestDeleteUserAdminToken(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		ts := newTestServer(t, enabled)
+		ts.user("alice")
+		vars := map[string]string{"nickname": "alice"}
+
+		r := ts.request(http.MethodDelete, "", vars, nil)
+		r.Header.Set(AdminTokenHeader, "wrong")
+		if w := ts.serve(ts.h.DeleteUser, r); w.Code == http.StatusOK {
+			t.Errorf("auth %v, wrong admin token: status 200: %s", enabled, w.Body)
+		}
+
+		r = ts.request(http.MethodDelete, "", vars, nil)
+		r.Header.Set(AdminTokenHeader, "admin-token")
+		w := ts.serve(ts.h.DeleteUser, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("auth %v, admin token: status %d, want 200: %s", enabled, w.Code, w.Body)
+		}
+		if _, err := ts.store.GetUser(context.Background(), "alice"); err == nil {
+			t.Errorf("auth %v: alice was not deleted", enabled)
+		}
+	}
+}
+
+func TestACLDenials(t *testing.T) {
+	ts := newTestServer(t, true)
+	for _, nickname := range []string{"alice", "bob", "mod", "troll"} {
+		ts.user(nickname)
+	}
+	id := ts.post("pirates", "alice", "ahoy")
+	post, err := ts.store.GetPost(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.role("pirates", "mod", acl.Moderator)
+	ts.role("pirates", "troll", acl.Banned)
+
+	thread := map[string]string{"slug_or_id": strconv.Itoa(post.Thread)}
+	postVars := map[string]string{"id": strconv.Itoa(id)}
+	roleOf := func(nickname, role string) models.ForumRole {
+		return models.ForumRole{Nickname: nickname, Role: role}
+	}
+	forum := map[string]string{"slug": "pirates"}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		caller  string
+		vars    map[string]string
+		body    interface{}
+		want    int
+	}{
+		{"banned posts", ts.h.CreatePost, "troll", thread, []models.Post{{Message: "spam"}}, http.StatusForbidden},
+		{"member posts", ts.h.CreatePost, "bob", thread, []models.Post{{Message: "hi"}}, http.StatusCreated},
+		{"member edits another's post", ts.h.ChangePost, "bob", postVars, models.Post{Message: "mine"}, http.StatusForbidden},
+		{"moderator edits another's post", ts.h.ChangePost, "mod", postVars, models.Post{Message: "tidied"}, http.StatusOK},
+		{"member grants a role", ts.h.SetForumRole, "bob", forum, roleOf("bob", acl.Moderator), http.StatusForbidden},
+		{"moderator grants a role", ts.h.SetForumRole, "mod", forum, roleOf("bob", acl.Moderator), http.StatusForbidden},
+		{"owner grants a role", ts.h.SetForumRole, "alice", forum, roleOf("bob", acl.Moderator), http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := ts.do(tt.handler, http.MethodPost, ts.login(tt.caller), tt.vars, tt.body)
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.want, w.Body)
+		}
+	}
+
+	// The ban holds while authentication is disabled too.
+	open := newTestServer(t, false)
+	open.user("alice")
+	open.user("troll")
+	id = open.post("pirates", "alice", "ahoy")
+	if post, err = open.store.GetPost(context.Background(), id); err != nil {
+		t.Fatal(err)
+	}
+	open.role("pirates", "troll", acl.Banned)
+	thread = map[string]string{"slug_or_id": strconv.Itoa(post.Thread)}
+	if w := open.do(open.h.CreatePost, http.MethodPost, "", thread, []models.Post{{Author: "troll", Message: "spam"}}); w.Code != http.StatusForbidden {
+		t.Errorf("banned posts without auth: status %d, want 403: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+	"server/httputils"
+	"server/models"
+	"server/store"
+)
+
+func (h *Handlers) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	category := models.Category{}
+
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	if category.Slug == "" || category.Title == "" {
+		mes := models.Message{}
+		mes.Message = "Category slug and title are required"
+		httputils.Respond(w, http.StatusBadRequest, mes)
+		return
+	}
+
+	if !h.admin(r) {
+		forbidden(w, "Creating categories requires the "+AdminTokenHeader+" admin credential")
+		return
+	}
+
+	result, err := h.store.CreateCategory(r.Context(), category)
+	if errors.Is(err, store.ErrConflict) {
+		httputils.Respond(w, http.StatusConflict, result)
+		return
+	}
+	if err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	httputils.Respond(w, http.StatusCreated, result)
+}
+
+// MoveForum reorders a forum or moves it under another parent or category.
+// The hierarchy is shared by the whole site, so it takes the admin token.
+func (h *Handlers) MoveForum(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	slug := params["slug"]
+
+	move := models.ForumMove{}
+
+	if err := json.NewDecoder(r.Body).Decode(&move); err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	if !h.admin(r) {
+		forbidden(w, "Moving forums requires the "+AdminTokenHeader+" admin credential")
+		return
+	}
+
+	forum, err := h.store.MoveForum(r.Context(), slug, move)
+	if store.IsNotFound(err, store.Forum) {
+		var nf *store.NotFoundError
+		errors.As(err, &nf)
+		notFound(w, "Can't find forum by slug: "+nf.Key)
+		return
+	}
+	if store.IsNotFound(err, store.Category) {
+		notFound(w, "Can't find category by slug: "+move.Category)
+		return
+	}
+	if errors.Is(err, store.ErrConflict) {
+		mes := models.Message{}
+		mes.Message = "Can't move forum " + slug + " under its own sub-forum: " + move.Parent
+		httputils.Respond(w, http.StatusConflict, mes)
+		return
+	}
+	if err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	httputils.Respond(w, http.StatusOK, forum)
+}
+
+// ForumTree returns the whole hierarchy with the counters of sub-forums
+// rolled up into their parents and categories.
+func (h *Handlers) ForumTree(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.store.ForumTree(r.Context())
+	if err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	httputils.Respond(w, http.StatusOK, tree)
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"server/auth"
+	"server/httputils"
+	"server/models"
+	"server/store"
+)
+
+// exporter writes the sections of a personal data export in the order of
+// store.ExportSections.
+type exporter interface {
+	section(name string) error
+	record(v interface{}) error
+	close() error
+}
+
+// jsonExport streams one JSON document: the profile object and an array for
+// every other section.
+type jsonExport struct {
+	w       io.Writer
+	current string
+	first   bool
+}
+
+func (e *jsonExport) section(name string) error {
+	prefix := "{"
+	if e.current != "" {
+		prefix = ","
+		if e.current != store.ExportProfile {
+			prefix = "],"
+		}
+	}
+	open := "["
+	if name == store.ExportProfile {
+		open = ""
+	}
+	e.current, e.first = name, true
+	_, err := io.WriteString(e.w, prefix+`"`+name+`":`+open)
+	return err
+}
+
+func (e *jsonExport) record(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if !e.first {
+		b = append([]byte{','}, b...)
+	}
+	e.first = false
+	_, err = e.w.Write(b)
+	return err
+}
+
+func (e *jsonExport) close() error {
+	_, err := io.WriteString(e.w, "]}\n")
+	return err
+}
+
+// zipExport streams a zip archive with one NDJSON file per section.
+type zipExport struct {
+	zip *zip.Writer
+	w   io.Writer
+}
+
+func (e *zipExport) section(name string) (err error) {
+	e.w, err = e.zip.Create(name + ".ndjson")
+	return err
+}
+
+func (e *zipExport) record(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(append(b, '\n'))
+	return err
+}
+
+func (e *zipExport) close() error {
+	return e.zip.Close()
+}
+
+// ExportUser streams everything the store holds about the user, as JSON or,
+// with ?format=zip, as a zip of NDJSON files. Records go out as the store
+// reads them, so an error halfway can only cut the response short. Only the
+// authenticated owner may export the account.
+func (h *Handlers) ExportUser(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	nickname := params["nickname"]
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		mes := models.Message{}
+		mes.Message = "Unknown export format: " + format
+		httputils.Respond(w, http.StatusBadRequest, mes)
+		return
+	}
+
+	if !h.actAsOwner(w, r, nickname, auth.ScopeRead) {
+		return
+	}
+
+	var e exporter
+	next := 0
+	// advance starts the response on first use and opens the sections up to
+	// store.ExportSections[last]; sections without records stay empty.
+	advance := func(last int) error {
+		if e == nil {
+			if format == "zip" {
+				w.Header().Set("Content-Type", "application/zip")
+				w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": nickname + ".zip"}))
+				e = &zipExport{zip: zip.NewWriter(w)}
+			} else {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": nickname + ".json"}))
+				e = &jsonExport{w: w}
+			}
+			w.WriteHeader(http.StatusOK)
+		}
+		for ; next <= last; next++ {
+			if err := e.section(store.ExportSections[next]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := h.store.ExportUser(r.Context(), nickname, func(section string, record interface{}) error {
+		for i := next; i < len(store.ExportSections); i++ {
+			if store.ExportSections[i] == section {
+				if err := advance(i); err != nil {
+					return err
+				}
+				break
+			}
+		}
+		return e.record(record)
+	})
+	if e == nil {
+		if store.IsNotFound(err, store.User) {
+			notFound(w, "Can't find user by nickname: "+nickname)
+			return
+		}
+		if err != nil {
+			httputils.Fail(w, r, err)
+			return
+		}
+	}
+	if err == nil {
+		err = advance(len(store.ExportSections) - 1)
+	}
+	if err == nil {
+		err = e.close()
+	}
+	if err != nil {
+		log.Printf("export of %s failed: %v", nickname, err)
+	}
+}
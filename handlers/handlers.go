@@ -1,26 +1,73 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/go-openapi/strfmt"
 	"github.com/gorilla/mux"
-	"github.com/jackc/pgx"
 	"net/http"
+	"server/acl"
+	"server/auth"
+	"server/diff"
 	"server/httputils"
 	"server/models"
+	"server/store"
 	"strconv"
 	"strings"
 	"time"
 )
 
 type Handlers struct {
-	conn *pgx.ConnPool
+	store      store.Store
+	auth       *auth.Auth
+	acl        *acl.ACL
+	adminToken string
 }
 
-func NewHandler(conn *pgx.ConnPool) *Handlers {
+// NewHandler serves the API from s. adminToken is the X-Admin-Token
+// credential for the site-wide endpoints, which stay closed while it is empty.
+func NewHandler(s store.Store, a *auth.Auth, adminToken string) *Handlers {
 	return &Handlers{
-		conn: conn,
+		store:      s,
+		auth:       a,
+		acl:        acl.New(s),
+		adminToken: adminToken,
+	}
+}
+
+func (h *Handlers) admin(r *http.Request) bool {
+	return adminRequest(r, h.adminToken)
+}
+
+// queryLimit reads ?limit=, 100 when it is missing, and answers 400 for a
+// negative one.
+func queryLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		return 100, true
+	}
+	if limit < 0 {
+		mes := models.Message{}
+		mes.Message = "Invalid limit: " + strconv.Itoa(limit)
+		httputils.Respond(w, http.StatusBadRequest, mes)
+		return limit, false
+	}
+	return limit, true
+}
+
+func notFound(w http.ResponseWriter, message string) {
+	mes := models.Message{}
+	mes.Message = message
+	httputils.Respond(w, http.StatusNotFound, mes)
+}
+
+func threadNotFound(w http.ResponseWriter, prefix string, ref store.ThreadRef) {
+	if ref.IsID() {
+		notFound(w, prefix+" by id: "+ref.String())
+	} else {
+		notFound(w, prefix+" by slug: "+ref.String())
 	}
 }
 
@@ -30,49 +77,41 @@ func (h *Handlers) CreateUser(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	nickname := params["nickname"]
 
-	user := models.User{Nickname: nickname}
+	var body struct {
+		models.User
+		Password string `json:"password"`
+	}
+	body.Nickname = nickname
 
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		httputils.Fail(w, r, err)
 		return
 	}
+	user := body.User
 
-	_, err := h.conn.Exec("insertUser",
-		user.Nickname,
-		user.Fullname,
-		user.About,
-		user.Email)
-
-	if driverErr, ok := err.(pgx.PgError); ok {
-		if driverErr.Code == "23505" {
-			row, err := h.conn.Query("selectDublicateUser", user.Nickname, user.Email)
-			if err != nil {
-				httputils.Respond(w, http.StatusInternalServerError, nil)
-				return
-			}
-			defer row.Close()
-			var users []models.User
-			for row.Next() {
-				user := models.User{}
-				err = row.Scan(
-					&user.Nickname,
-					&user.Fullname,
-					&user.About,
-					&user.Email)
-				if err != nil {
-					httputils.Respond(w, http.StatusInternalServerError, nil)
-					return
-				}
-
-				users = append(users, user)
-			}
+	if h.auth.Enabled() && body.Password == "" {
+		mes := models.Message{}
+		mes.Message = "Password is required"
+		httputils.Respond(w, http.StatusBadRequest, mes)
+		return
+	}
 
-			httputils.Respond(w, http.StatusConflict, users)
+	var hash string
+	if body.Password != "" {
+		var err error
+		if hash, err = auth.HashPassword(body.Password); err != nil {
+			httputils.Fail(w, r, err)
 			return
 		}
 	}
+
+	users, err := h.store.CreateUser(r.Context(), user, hash)
+	if errors.Is(err, store.ErrConflict) {
+		httputils.Respond(w, http.StatusConflict, users)
+		return
+	}
 	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+		httputils.Fail(w, r, err)
 		return
 	}
 
@@ -83,22 +122,13 @@ func (h *Handlers) GetUser(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	nickname := params["nickname"]
 
-	user := models.User{}
-
-	row, _ := h.conn.Query("selectUser", nickname)
-
-	if !row.Next() {
-		mes := models.Message{}
-		mes.Message = "Can't find user by nickname: " + nickname
-		httputils.Respond(w, http.StatusNotFound, mes)
+	user, err := h.store.GetUser(r.Context(), nickname)
+	if store.IsNotFound(err, store.User) {
+		notFound(w, "Can't find user by nickname: "+nickname)
 		return
 	}
-
-	defer row.Close()
-
-	err := row.Scan(&user.Nickname, &user.Fullname, &user.About, &user.Email)
 	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+		httputils.Fail(w, r, err)
 		return
 	}
 
@@ -112,1206 +142,1038 @@ func (h *Handlers) ChangeUser(w http.ResponseWriter, r *http.Request) {
 	user := models.User{Nickname: nickname}
 
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+		httputils.Fail(w, r, err)
 		return
 	}
+	// The path decides whose profile changes, never the body.
+	user.Nickname = nickname
 
-	tx, err := h.conn.Begin()
-	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+	if !h.actAs(w, r, nickname, auth.ScopeAdmin) {
 		return
 	}
 
-	row, _ := tx.Query("checkUser", nickname)
-
-	if !row.Next() {
-		mes := models.Message{}
-		mes.Message = "Can't find user by nickname: " + nickname
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusNotFound, mes)
-		return
-	}
-	row.Close()
-
-	err = tx.QueryRow(
-		"changeUser",
-		user.Fullname,
-		user.About,
-		user.Email,
-		user.Nickname).Scan(
-		&user.Nickname,
-		&user.Fullname,
-		&user.About,
-		&user.Email,
-	)
-	if err != nil {
+	user, err := h.store.UpdateUser(r.Context(), user)
+	if store.IsNotFound(err, store.User) {
+		notFound(w, "Can't find user by nickname: "+nickname)
+		return
+	}
+	if errors.Is(err, store.ErrConflict) {
 		mes := models.Message{}
 		mes.Message = "This email is already registered by user: " + nickname
-		_ = tx.Rollback()
 		httputils.Respond(w, http.StatusConflict, mes)
 		return
 	}
-
-	err = tx.Commit()
 	if err != nil {
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+		httputils.Fail(w, r, err)
 		return
 	}
 
 	httputils.Respond(w, http.StatusOK, user)
 }
 
-// FORUM
-
-func (h *Handlers) CreateForum(w http.ResponseWriter, r *http.Request) {
-	forum := models.Forum{}
-
-	if err := json.NewDecoder(r.Body).Decode(&forum); err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+// SearchUsers finds users whose nickname or full name starts with q, then
+// the ones that only resemble it. ?since= takes the last nickname of the
+// previous page and ?forum= keeps the members of one forum.
+func (h *Handlers) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		mes := models.Message{}
+		mes.Message = "Search query is required"
+		httputils.Respond(w, http.StatusBadRequest, mes)
 		return
 	}
 
-	tx, err := h.conn.Begin()
-	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+	limit, ok := queryLimit(w, r)
+	if !ok {
 		return
 	}
 
-	err = tx.QueryRow("checkUser", forum.User).Scan(&forum.User)
+	forum := r.URL.Query().Get("forum")
+	users, err := h.store.SearchUsers(r.Context(), store.UserSearchQuery{
+		Query: query,
+		Forum: forum,
+		Since: r.URL.Query().Get("since"),
+		Limit: limit,
+	})
+	if store.IsNotFound(err, store.Forum) {
+		notFound(w, "Can't find forum by slug: "+forum)
+		return
+	}
 	if err != nil {
-		mes := models.Message{}
-		mes.Message = "Can't find user with nickname: " + forum.User
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusNotFound, mes)
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	_, err = tx.Exec(
-		"insertForum",
-		&forum.Title, &forum.User, &forum.Slug)
+	httputils.Respond(w, http.StatusOK, users)
+}
+
+// activityQuery reads ?limit=, ?since=, ?since_id=, ?desc= and ?forum= for
+// the user listings. They are newest first unless ?desc=false. To page,
+// pass the created time and id of the last item as since and since_id;
+// since alone is an inclusive timestamp like in GetForumThreads.
+func activityQuery(w http.ResponseWriter, r *http.Request) (store.ActivityQuery, bool) {
+	limit, ok := queryLimit(w, r)
+	if !ok {
+		return store.ActivityQuery{}, false
+	}
 
+	desc, err := strconv.ParseBool(r.URL.Query().Get("desc"))
 	if err != nil {
-		_ = tx.Rollback()
-		tx, err = h.conn.Begin()
-		if err != nil {
-			httputils.Respond(w, http.StatusInternalServerError, nil)
-			return
-		}
+		desc = true
+	}
 
-		var result models.Forum
-		err = tx.QueryRow("selectForum", forum.Slug).Scan(
-			&result.Title, &result.User, &result.Slug, &result.Posts, &result.Threads)
+	query := store.ActivityQuery{Limit: limit, Desc: desc, Forum: r.URL.Query().Get("forum")}
+	if since := r.URL.Query().Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339Nano, since)
 		if err != nil {
-			_ = tx.Rollback()
-			httputils.Respond(w, http.StatusInternalServerError, nil)
-			return
+			mes := models.Message{}
+			mes.Message = "Invalid since timestamp: " + since
+			httputils.Respond(w, http.StatusBadRequest, mes)
+			return query, false
 		}
-		err = tx.Commit()
-		if err != nil {
-			_ = tx.Rollback()
-			httputils.Respond(w, http.StatusInternalServerError, nil)
-			return
+		query.Since = &t
+	}
+	if sinceID := r.URL.Query().Get("since_id"); sinceID != "" {
+		id, err := strconv.Atoi(sinceID)
+		if err != nil || id <= 0 || query.Since == nil {
+			mes := models.Message{}
+			mes.Message = "Invalid since_id, it needs since and a positive id: " + sinceID
+			httputils.Respond(w, http.StatusBadRequest, mes)
+			return query, false
 		}
-
-		httputils.Respond(w, http.StatusConflict, result)
-		return
-
+		query.SinceID = id
 	}
+	return query, true
+}
 
-	err = tx.Commit()
-	httputils.Respond(w, http.StatusCreated, forum)
+// activityNotFound answers for a missing user or ?forum= and reports whether
+// it did.
+func activityNotFound(w http.ResponseWriter, err error, nickname string, query store.ActivityQuery) bool {
+	switch {
+	case store.IsNotFound(err, store.User):
+		notFound(w, "Can't find user by nickname: "+nickname)
+	case store.IsNotFound(err, store.Forum):
+		notFound(w, "Can't find forum by slug: "+query.Forum)
+	default:
+		return false
+	}
+	return true
 }
 
-func (h *Handlers) GetForum(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetUserThreads(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
-	slug := params["slug"]
+	nickname := params["nickname"]
 
-	forum := models.Forum{}
+	query, ok := activityQuery(w, r)
+	if !ok {
+		return
+	}
 
-	err := h.conn.QueryRow("selectForum", slug).Scan(
-		&forum.Title, &forum.User, &forum.Slug, &forum.Posts, &forum.Threads)
+	threads, err := h.store.UserThreads(r.Context(), nickname, query)
+	if activityNotFound(w, err, nickname, query) {
+		return
+	}
 	if err != nil {
-		mes := models.Message{}
-		mes.Message = "Can't find forum with slug: " + slug
-		httputils.Respond(w, http.StatusNotFound, mes)
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	httputils.Respond(w, http.StatusOK, forum)
+	httputils.Respond(w, http.StatusOK, threads)
 }
 
-func (h *Handlers) CreateThread(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetUserPosts(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
-	forum := params["slug"]
-
-	thread := models.Thread{}
-
-	if err := json.NewDecoder(r.Body).Decode(&thread); err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
-		return
-	}
+	nickname := params["nickname"]
 
-	tx, err := h.conn.Begin()
-	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+	query, ok := activityQuery(w, r)
+	if !ok {
 		return
 	}
 
-	err = tx.QueryRow("checkUser", thread.Author).Scan(&thread.Author)
-	if err != nil {
-		mes := models.Message{}
-		mes.Message = "Can't find thread author by nickname: " + thread.Author
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusNotFound, mes)
+	posts, err := h.store.UserPosts(r.Context(), nickname, query)
+	if activityNotFound(w, err, nickname, query) {
 		return
 	}
-
-	err = tx.QueryRow("checkForum", forum).Scan(&thread.Forum)
 	if err != nil {
-		mes := models.Message{}
-		mes.Message = "Can't find thread forum by slug: " + thread.Forum
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusNotFound, mes)
+		httputils.Fail(w, r, err)
 		return
 	}
-	if thread.Created.String() == "" {
-		thread.Created = time.Now()
-	}
-
-	err = tx.QueryRow("insertThread",
-		thread.Title,
-		thread.Author,
-		thread.Forum,
-		thread.Message,
-		thread.Votes,
-		thread.Slug,
-		thread.Created).Scan(&thread.Id)
 
-	if err != nil {
-		_ = tx.Rollback()
-		tx, err = h.conn.Begin()
-		if err != nil {
-			httputils.Respond(w, http.StatusInternalServerError, nil)
-			return
-		}
+	httputils.Respond(w, http.StatusOK, posts)
+}
 
-		var result models.Thread
-		err := tx.QueryRow("selectThread",
-			thread.Slug).Scan(
-			&result.Id,
-			&result.Title,
-			&result.Author,
-			&result.Forum,
-			&result.Message,
-			&result.Votes,
-			&result.Slug,
-			&result.Created)
-		if err != nil {
-			_ = tx.Rollback()
-			httputils.Respond(w, http.StatusInternalServerError, nil)
-			return
-		}
+// DeleteUser anonymizes the account: its forums, threads and posts move to
+// a fresh ghost user, its votes are withdrawn, and the audit log records the
+// deletion under the ghost nickname only. Only the authenticated owner or a
+// holder of the admin token may delete the account.
+func (h *Handlers) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	nickname := params["nickname"]
 
-		err = tx.Commit()
-		if err != nil {
-			_ = tx.Rollback()
-			httputils.Respond(w, http.StatusInternalServerError, nil)
+	// The admin token lets compliance deletions through, also while
+	// authentication is disabled.
+	actor := "admin"
+	if !h.admin(r) {
+		if !h.actAsOwner(w, r, nickname, auth.ScopeAdmin) {
 			return
 		}
+		actor = "self"
+	}
 
-		httputils.Respond(w, http.StatusConflict, result)
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		httputils.Fail(w, r, err)
 		return
 	}
+	ghost := models.User{
+		Nickname: "ghost-" + hex.EncodeToString(b),
+		Fullname: "Deleted user",
+	}
+	ghost.Email = ghost.Nickname + "@deleted.invalid"
 
-	err = tx.Commit()
+	result, err := h.store.AnonymizeUser(r.Context(), nickname, ghost)
+	if store.IsNotFound(err, store.User) {
+		notFound(w, "Can't find user by nickname: "+nickname)
+		return
+	}
 	if err != nil {
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	httputils.Respond(w, http.StatusCreated, thread)
+	// The nickname is not kept, not even as the actor.
+	writeAudit(h.store, models.AuditEntry{
+		Created:   time.Now(),
+		Action:    "delete user",
+		Scope:     result.Ghost,
+		Actor:     actor,
+		RequestID: httputils.RequestID(r.Context()),
+		Remote:    r.RemoteAddr,
+		Result: fmt.Sprintf("anonymized: %d forums, %d threads, %d posts, %d votes removed",
+			result.Forums, result.Threads, result.Posts, result.Votes),
+	})
+
+	httputils.Respond(w, http.StatusOK, result)
 }
 
-func (h *Handlers) GetForumUsers(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	forum := params["slug"]
+// FORUM
 
-	tx, err := h.conn.Begin()
-	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+func (h *Handlers) CreateForum(w http.ResponseWriter, r *http.Request) {
+	forum := models.Forum{}
+
+	if err := json.NewDecoder(r.Body).Decode(&forum); err != nil {
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	row, _ := tx.Query("checkForum", forum)
-	if !row.Next() {
-		mes := models.Message{}
-		mes.Message = "Can't find forum by slug: " + forum
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusNotFound, mes)
+	bindNickname(r, &forum.User)
+	if !h.actAs(w, r, forum.User, auth.ScopePost) {
 		return
 	}
-
-	row.Close()
-
-	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
-	if err != nil {
-		limit = 100
+	// Only the owner of the parent forum may add sub-forums to it.
+	if forum.Parent != "" && !h.manageForum(w, r, forum.Parent) {
+		return
 	}
 
-	since := r.URL.Query().Get("since")
-	desc, err := strconv.ParseBool(r.URL.Query().Get("desc"))
-	if err != nil {
-		desc = false
+	result, err := h.store.CreateForum(r.Context(), forum)
+	if store.IsNotFound(err, store.User) {
+		notFound(w, "Can't find user with nickname: "+forum.User)
+		return
 	}
-
-	var users []models.User
-	if since == "" {
-		if desc {
-			row, err = tx.Query(
-				"selectUserOrderDesc",
-				&forum,
-				&limit)
-		} else {
-			row, err = tx.Query(
-				"selectUserOrder",
-				&forum,
-				&limit)
-		}
-	} else {
-		if desc {
-			row, err = tx.Query(
-				"selectUserWhereOrderDesc",
-				&forum,
-				&limit,
-				&since)
-		} else {
-			row, err = tx.Query(
-				"selectUserWhereOrder",
-				&forum,
-				&limit,
-				&since)
-		}
+	if store.IsNotFound(err, store.Forum) {
+		notFound(w, "Can't find parent forum by slug: "+forum.Parent)
+		return
 	}
-
-	if err != nil {
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+	if store.IsNotFound(err, store.Category) {
+		notFound(w, "Can't find category by slug: "+forum.Category)
 		return
 	}
-
-	for row.Next() {
-		u := models.User{}
-		err = row.Scan(
-			&u.Nickname,
-			&u.Fullname,
-			&u.About,
-			&u.Email,
-		)
-		if err != nil {
-			_ = tx.Rollback()
-			httputils.Respond(w, http.StatusInternalServerError, nil)
-			return
-		}
-		users = append(users, u)
+	if errors.Is(err, store.ErrConflict) && result.Slug == "" {
+		mes := models.Message{}
+		mes.Message = "Forum slug is already taken: " + forum.Slug
+		httputils.Respond(w, http.StatusConflict, mes)
+		return
 	}
-
-	if users == nil {
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusOK, []models.User{})
-	} else {
-		err = tx.Commit()
-		if err != nil {
-			_ = tx.Rollback()
-			httputils.Respond(w, http.StatusInternalServerError, nil)
-			return
-		}
-		httputils.Respond(w, http.StatusOK, users)
+	if errors.Is(err, store.ErrConflict) {
+		httputils.Respond(w, http.StatusConflict, result)
+		return
+	}
+	if err != nil {
+		httputils.Fail(w, r, err)
+		return
 	}
+
+	httputils.Respond(w, http.StatusCreated, result)
 }
 
-func (h *Handlers) GetForumThreads(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetForum(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
-	forum := params["slug"]
+	slug := params["slug"]
 
-	tx, err := h.conn.Begin()
-	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+	forum, err := h.store.GetForum(r.Context(), slug)
+	if store.IsNotFound(err, store.Forum) {
+		notFound(w, "Can't find forum with slug: "+slug)
 		return
 	}
-
-	row, _ := tx.Query("checkForum", forum)
-	if !row.Next() {
-		mes := models.Message{}
-		mes.Message = "Can't find forum by slug: " + forum
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusNotFound, mes)
+	if err != nil {
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	row.Close()
+	httputils.Respond(w, http.StatusOK, forum)
+}
 
-	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
-	if err != nil {
-		limit = 100
+func (h *Handlers) GetForums(w http.ResponseWriter, r *http.Request) {
+	limit, ok := queryLimit(w, r)
+	if !ok {
+		return
 	}
 
-	since := r.URL.Query().Get("since")
 	desc, err := strconv.ParseBool(r.URL.Query().Get("desc"))
 	if err != nil {
 		desc = false
 	}
 
-	var threads []models.Thread
-	if since == "" {
-		if desc {
-			row, err = tx.Query("selectThreadOrderDesc",
-				&forum,
-				&limit)
-		} else {
-			row, err = tx.Query("selectThreadOrder",
-				&forum,
-				&limit)
-		}
-	} else {
-		if desc {
-			row, err = tx.Query("selectThreadWhereOrderDesc",
-				&forum,
-				&limit,
-				&since)
-		} else {
-			row, err = tx.Query("selectThreadWhereOrder",
-				&forum,
-				&limit,
-				&since)
-		}
+	order := r.URL.Query().Get("sort")
+	switch order {
+	case "":
+		order = store.ForumSortSlug
+	case store.ForumSortSlug, store.ForumSortTitle, store.ForumSortPosts, store.ForumSortThreads:
+	default:
+		mes := models.Message{}
+		mes.Message = "Unknown sort: " + order
+		httputils.Respond(w, http.StatusBadRequest, mes)
+		return
 	}
 
+	owner := r.URL.Query().Get("owner")
+	forums, err := h.store.Forums(r.Context(), store.ForumsQuery{
+		Limit: limit,
+		Since: r.URL.Query().Get("since"),
+		Sort:  order,
+		Owner: owner,
+		Desc:  desc,
+	})
+	if store.IsNotFound(err, store.User) {
+		notFound(w, "Can't find user by nickname: "+owner)
+		return
+	}
 	if err != nil {
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusInternalServerError, nil)
-		return
-	}
-
-	for row.Next() {
-		t := models.Thread{}
-		err = row.Scan(
-			&t.Id,
-			&t.Title,
-			&t.Author,
-			&t.Forum,
-			&t.Message,
-			&t.Votes,
-			&t.Slug,
-			&t.Created)
-		if err != nil {
-			_ = tx.Rollback()
-			httputils.Respond(w, http.StatusInternalServerError, nil)
-			return
-		}
-		threads = append(threads, t)
+		httputils.Fail(w, r, err)
+		return
 	}
 
-	row.Close()
-
-	if threads != nil {
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusOK, threads)
-	} else {
-		err = tx.Commit()
-		if err != nil {
-			_ = tx.Rollback()
-			httputils.Respond(w, http.StatusInternalServerError, nil)
-			return
-		}
-		httputils.Respond(w, http.StatusOK, []models.Thread{})
-	}
+	httputils.Respond(w, http.StatusOK, forums)
 }
 
-// POST
-
-func (h *Handlers) GetPost(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) UpdateForum(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
-	post := params["id"]
+	slug := params["slug"]
 
-	var related []string
-	related = strings.Split(r.URL.Query().Get("related"), ",")
+	var update models.Forum
 
-	var result struct {
-		Post   *models.Post   `json:"post,omitempty"`
-		Thread *models.Thread `json:"thread,omitempty"`
-		Forum  *models.Forum  `json:"forum,omitempty"`
-		User   *models.User   `json:"author,omitempty"`
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		httputils.Fail(w, r, err)
+		return
 	}
 
-	tx, err := h.conn.Begin()
-	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+	if !h.manageForum(w, r, slug) {
 		return
 	}
 
-	var p models.Post
-	err = tx.QueryRow( "selectPost", post).Scan(
-		&p.Id, &p.Parent, &p.Author, &p.Message, &p.IsEdited, &p.Forum, &p.Thread, &p.Created)
-	if err != nil {
-		mes := models.Message{}
-		mes.Message = "Can't find post with id: " + post
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusNotFound, mes)
+	forum, err := h.store.UpdateForum(r.Context(), slug, update)
+	if store.IsNotFound(err, store.Forum) {
+		notFound(w, "Can't find forum by slug: "+slug)
 		return
 	}
-
-	result.Post = &p
-
-	var user models.User
-	var forum models.Forum
-	var thread models.Thread
-
-	for _, item := range related {
-		if item == "user" {
-			err = tx.QueryRow( "selectUser", result.Post.Author).Scan(
-				&user.Nickname, &user.Fullname, &user.About, &user.Email)
-			result.User = &user
-		}
-		if item == "forum" {
-			err = tx.QueryRow( "selectForum", result.Post.Forum).Scan(
-				&forum.Title, &forum.User, &forum.Slug, &forum.Posts, &forum.Threads)
-			result.Forum = &forum
-		}
-		if item == "thread" {
-			err = tx.QueryRow( "selectThreadById", result.Post.Thread).Scan(
-				&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created)
-			result.Thread = &thread
-		}
-		if err != nil {
-			_ = tx.Rollback()
-			httputils.Respond(w, http.StatusInternalServerError, nil)
-			return
-		}
+	if store.IsNotFound(err, store.User) {
+		notFound(w, "Can't find user by nickname: "+update.User)
+		return
 	}
-
-	err = tx.Commit()
 	if err != nil {
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	httputils.Respond(w, http.StatusOK, result)
+	httputils.Respond(w, http.StatusOK, forum)
 }
 
-func (h *Handlers) ChangePost(w http.ResponseWriter, r *http.Request) {
+// DeleteForum removes the forum with everything in it, or with ?soft=true
+// only hides it. A hidden forum can still be removed for good by its owner.
+func (h *Handlers) DeleteForum(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
-	id, err := strconv.Atoi(params["id"])
+	slug := params["slug"]
+	soft := r.URL.Query().Get("soft") == "true"
+
+	forum, err := h.store.GetForum(r.Context(), slug)
+	deleted := false
+	if store.IsNotFound(err, store.Forum) && !soft {
+		forum, err = h.store.GetDeletedForum(r.Context(), slug)
+		deleted = err == nil
+	}
+	if store.IsNotFound(err, store.Forum) {
+		notFound(w, "Can't find forum by slug: "+slug)
+		return
+	}
 	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	post := models.Post{Id: id}
-
-	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+	if deleted && !h.ownForum(w, r, forum) {
 		return
 	}
-
-	tx, err := h.conn.Begin()
-	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+	if !deleted && !h.manageForum(w, r, slug) {
 		return
 	}
 
-	err = tx.QueryRow("updatePost",
-		post.Message,
-		post.Id).Scan(
-		&post.Id,
-		&post.Parent,
-		&post.Author,
-		&post.Message,
-		&post.IsEdited,
-		&post.Forum,
-		&post.Thread,
-		&post.Created,
-	)
-
-	if err != nil {
-		mes := models.Message{}
-		mes.Message = "Can't find post with id: " + strconv.Itoa(id)
-		httputils.Respond(w, http.StatusNotFound, mes)
-		_ = tx.Rollback()
+	err = h.store.DeleteForum(r.Context(), forum.Slug, soft)
+	if store.IsNotFound(err, store.Forum) {
+		notFound(w, "Can't find forum by slug: "+slug)
 		return
 	}
-
-	err = tx.Commit()
 	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
-		_ = tx.Rollback()
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	httputils.Respond(w, http.StatusOK, post)
+	httputils.Respond(w, http.StatusOK, forum)
 }
 
-// THREAD
-
-func (h *Handlers) CreatePost(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) CreateThread(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
-	thread := params["slug_or_id"]
+	forum := params["slug"]
 
-	isId, err := strconv.Atoi(thread)
-	if err != nil {
-		isId = -1
-	}
-
-	var posts []models.Post
+	thread := models.Thread{}
 
-	if err := json.NewDecoder(r.Body).Decode(&posts); err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+	if err := json.NewDecoder(r.Body).Decode(&thread); err != nil {
+		httputils.Fail(w, r, err)
 		return
 	}
+	thread.Forum = forum
 
-	var mes models.Message
-
-	var info models.Thread
-
-	tx, err := h.conn.Begin()
-	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+	bindNickname(r, &thread.Author)
+	if !h.actAs(w, r, thread.Author, auth.ScopePost) {
 		return
 	}
-
-	if isId == -1 {
-		err = tx.QueryRow("selectIdForumThreadBySlug", thread).Scan(&info.Id, &info.Forum)
-		if err != nil {
-			mes.Message = "Can't find post thread by slug: " + thread
-		}
-	} else {
-		err = tx.QueryRow("selectIdForumThreadById", isId).Scan(&info.Id, &info.Forum)
-		if err != nil {
-			mes.Message = "Can't find post thread by id: " + strconv.Itoa(isId)
-		}
+	if !h.allowed(w, r, forum, thread.Author, acl.CreateThread) {
+		return
 	}
 
-	if err != nil {
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusNotFound, mes)
+	result, err := h.store.CreateThread(r.Context(), thread)
+	if store.IsNotFound(err, store.User) {
+		notFound(w, "Can't find thread author by nickname: "+thread.Author)
 		return
 	}
-
-	if len(posts) == 0 {
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusCreated, posts)
+	if store.IsNotFound(err, store.Forum) {
+		notFound(w, "Can't find thread forum by slug: "+forum)
+		return
+	}
+	if errors.Is(err, store.ErrConflict) && result.Id == 0 {
+		mes := models.Message{}
+		mes.Message = "Thread slug is already taken: " + thread.Slug
+		httputils.Respond(w, http.StatusConflict, mes)
+		return
+	}
+	if errors.Is(err, store.ErrConflict) {
+		httputils.Respond(w, http.StatusConflict, result)
+		return
+	}
+	if err != nil {
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	create := strfmt.DateTime(time.Now())
-
-	var values string
-	var args []interface{}
-	l := len(posts) - 1
-
-	if posts[0].Parent != 0 {
-		var parent int
-
-		row, _ := tx.Query("selectThreadIdFromPost", posts[0].Parent)
+	httputils.Respond(w, http.StatusCreated, result)
+}
 
-		if row.Next() {
-			err := row.Scan(&parent)
-			if err != nil {
-				_ = tx.Rollback()
-				return
-			}
-		}
+func (h *Handlers) GetForumUsers(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	forum := params["slug"]
 
-		row.Close()
+	limit, ok := queryLimit(w, r)
+	if !ok {
+		return
+	}
 
-		if parent != info.Id {
-			mes := models.Message{}
-			mes.Message = "Parent post was created in another thread"
-			httputils.Respond(w, http.StatusConflict, mes)
-			_ = tx.Rollback()
-			return
-		}
+	desc, err := strconv.ParseBool(r.URL.Query().Get("desc"))
+	if err != nil {
+		desc = false
 	}
 
-	for i, item := range posts {
-		row, _ := tx.Query("selectUser", item.Author)
-		if !row.Next() {
-			mes := models.Message{}
-			mes.Message = "Can't find post author by nickname: " + item.Author
-			httputils.Respond(w, http.StatusNotFound, mes)
-			_ = tx.Rollback()
-			return
-		}
+	users, err := h.store.ForumUsers(r.Context(), forum, store.UsersQuery{
+		Limit: limit,
+		Since: r.URL.Query().Get("since"),
+		Desc:  desc,
+	})
+	if store.IsNotFound(err, store.Forum) {
+		notFound(w, "Can't find forum by slug: "+forum)
+		return
+	}
+	if err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
 
-		row.Close()
+	httputils.Respond(w, http.StatusOK, users)
+}
 
-		item.Thread = info.Id
-		item.Forum = info.Forum
+func (h *Handlers) GetForumThreads(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	forum := params["slug"]
 
-		values += fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)",
-			i*6+1, i*6+2, i*6+3, i*6+4, i*6+5, i*6+6)
-		args = append(args, item.Parent, item.Author, item.Message, item.Forum, item.Thread, create)
-		if i != l {
-			values += ","
-		}
+	limit, ok := queryLimit(w, r)
+	if !ok {
+		return
 	}
 
-	query := "INSERT INTO forum.post(parent, author, message, forum, thread, created) VALUES " + values + " RETURNING id, parent, author, message, isEdited, forum, thread, created"
-	posts = []models.Post{}
-	row, err := tx.Query(query, args...)
-
+	desc, err := strconv.ParseBool(r.URL.Query().Get("desc"))
 	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
-		_ = tx.Rollback()
-		return
-	}
-
-	for row.Next() {
-		p := models.Post{}
-		err = row.Scan(
-			&p.Id,
-			&p.Parent,
-			&p.Author,
-			&p.Message,
-			&p.IsEdited,
-			&p.Forum,
-			&p.Thread,
-			&p.Created)
+		desc = false
+	}
+
+	query := store.ThreadsQuery{Limit: limit, Desc: desc}
+	if since := r.URL.Query().Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339Nano, since)
 		if err != nil {
-			_ = tx.Rollback()
-			httputils.Respond(w, http.StatusInternalServerError, nil)
+			mes := models.Message{}
+			mes.Message = "Invalid since timestamp: " + since
+			httputils.Respond(w, http.StatusBadRequest, mes)
 			return
 		}
-
-		posts = append(posts, p)
+		query.Since = &t
 	}
 
-	err = tx.Commit()
+	threads, err := h.store.ForumThreads(r.Context(), forum, query)
+	if store.IsNotFound(err, store.Forum) {
+		notFound(w, "Can't find forum by slug: "+forum)
+		return
+	}
 	if err != nil {
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	httputils.Respond(w, http.StatusCreated, posts)
+	httputils.Respond(w, http.StatusOK, threads)
 }
 
-func (h *Handlers) GetThread(w http.ResponseWriter, r *http.Request) {
+// POST
+
+func (h *Handlers) GetPost(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
-	thread := params["slug_or_id"]
+	post := params["id"]
 
-	isId, err := strconv.Atoi(thread)
-	if err != nil {
-		isId = -1
+	var related []string
+	related = strings.Split(r.URL.Query().Get("related"), ",")
+
+	var result struct {
+		Post   *models.Post   `json:"post,omitempty"`
+		Thread *models.Thread `json:"thread,omitempty"`
+		Forum  *models.Forum  `json:"forum,omitempty"`
+		User   *models.User   `json:"author,omitempty"`
 	}
 
-	var result models.Thread
-	if isId == -1 {
-		err = h.conn.QueryRow( "selectThreadBySlug", thread).Scan(
-			&result.Id, &result.Title, &result.Author, &result.Forum, &result.Message, &result.Votes, &result.Slug, &result.Created)
-	} else {
-		err = h.conn.QueryRow( "selectThreadById", isId).Scan(
-			&result.Id, &result.Title, &result.Author,  &result.Forum, &result.Message, &result.Votes, &result.Slug, &result.Created)
+	id, err := strconv.Atoi(post)
+	if err != nil {
+		notFound(w, "Can't find post with id: "+post)
+		return
 	}
 
+	p, err := h.store.GetPost(r.Context(), id)
+	if store.IsNotFound(err, store.Post) {
+		notFound(w, "Can't find post with id: "+post)
+		return
+	}
 	if err != nil {
-		mes := models.Message{}
-		mes.Message = "Can't find thread by slug or id: " + thread
-		httputils.Respond(w, http.StatusNotFound, mes)
+		httputils.Fail(w, r, err)
 		return
 	}
 
+	result.Post = &p
+
+	for _, item := range related {
+		switch item {
+		case "user":
+			// A tombstone hides its author.
+			if p.IsDeleted {
+				continue
+			}
+			user, err := h.store.GetUser(r.Context(), p.Author)
+			if store.IsNotFound(err, store.User) {
+				notFound(w, "Can't find user by nickname: "+p.Author)
+				return
+			}
+			if err != nil {
+				httputils.Fail(w, r, err)
+				return
+			}
+			result.User = &user
+		case "forum":
+			forum, err := h.store.GetForum(r.Context(), p.Forum)
+			if store.IsNotFound(err, store.Forum) {
+				notFound(w, "Can't find forum by slug: "+p.Forum)
+				return
+			}
+			if err != nil {
+				httputils.Fail(w, r, err)
+				return
+			}
+			result.Forum = &forum
+		case "thread":
+			thread, err := h.store.GetThread(r.Context(), store.ThreadRef{ID: p.Thread})
+			if store.IsNotFound(err, store.Thread) {
+				notFound(w, "Can't find thread by slug or id: "+strconv.Itoa(p.Thread))
+				return
+			}
+			if err != nil {
+				httputils.Fail(w, r, err)
+				return
+			}
+			result.Thread = &thread
+		}
+	}
+
 	httputils.Respond(w, http.StatusOK, result)
 }
 
-func (h *Handlers) ChangeThread(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) ChangePost(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
-	thread := params["slug_or_id"]
-
-	isId, err := strconv.Atoi(thread)
+	id, err := strconv.Atoi(params["id"])
 	if err != nil {
-		isId = -1
+		httputils.Fail(w, r, err)
+		return
 	}
 
-	result := models.Thread{Slug: thread, Id: isId}
-	if err := json.NewDecoder(r.Body).Decode(&result); err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+	post := models.Post{Id: id}
+
+	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	var mes models.Message
-	tx, err := h.conn.Begin()
-	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
-		return
-	}
-
-	if isId == -1 {
-		err = tx.QueryRow("updateThreadBySlug",
-			result.Title,
-			result.Message,
-			result.Slug).Scan(
-			&result.Id,
-			&result.Title,
-			&result.Author,
-			&result.Forum,
-			&result.Message,
-			&result.Votes,
-			&result.Slug,
-			&result.Created)
-		mes.Message = "Can't find thread by slug: " + thread
-	} else {
-		err = tx.QueryRow("updateThreadById",
-			result.Title,
-			result.Message,
-			result.Id).Scan(
-			&result.Id,
-			&result.Title,
-			&result.Author,
-			&result.Forum,
-			&result.Message,
-			&result.Votes,
-			&result.Slug,
-			&result.Created)
-		mes.Message = "Can't find forum by id: " + thread
+	if h.auth.Enabled() {
+		existing, err := h.store.GetPost(r.Context(), id)
+		if store.IsNotFound(err, store.Post) {
+			notFound(w, "Can't find post with id: "+strconv.Itoa(id))
+			return
+		}
+		if err != nil {
+			httputils.Fail(w, r, err)
+			return
+		}
+		if !h.mayEdit(w, r, existing.Forum, existing.Author) {
+			return
+		}
 	}
 
-	if err != nil {
-		httputils.Respond(w, http.StatusNotFound, mes)
-		_ = tx.Rollback()
+	post, err = h.store.UpdatePost(r.Context(), id, post.Message, auth.Nickname(r.Context()))
+	if store.IsNotFound(err, store.Post) {
+		notFound(w, "Can't find post with id: "+strconv.Itoa(id))
 		return
 	}
-
-	err = tx.Commit()
 	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
-		_ = tx.Rollback()
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	httputils.Respond(w, http.StatusOK, result)
+	httputils.Respond(w, http.StatusOK, post)
 }
 
-func (h *Handlers) CreateVote(w http.ResponseWriter, r *http.Request) {
+// PostHistory lists the edits of the post, oldest first, each with the line
+// diff from the text it replaced to the text that replaced it.
+func (h *Handlers) PostHistory(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
-	thread := params["slug_or_id"]
-
-	isId, err := strconv.Atoi(thread)
+	id, err := strconv.Atoi(params["id"])
 	if err != nil {
-		isId = -1
+		notFound(w, "Can't find post with id: "+params["id"])
+		return
 	}
 
-	var vote models.Vote
-
-	if err := json.NewDecoder(r.Body).Decode(&vote); err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+	post, err := h.store.GetPost(r.Context(), id)
+	if store.IsNotFound(err, store.Post) {
+		notFound(w, "Can't find post with id: "+strconv.Itoa(id))
 		return
 	}
-
-	tx, err := h.conn.Begin()
 	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	row, _ := tx.Query("checkUser", vote.Nickname)
-	if !row.Next() {
-		mes := models.Message{}
-		mes.Message = "Can't find user by nickname: " + vote.Nickname
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusNotFound, mes)
+	revisions, err := h.store.PostRevisions(r.Context(), id)
+	if store.IsNotFound(err, store.Post) {
+		notFound(w, "Can't find post with id: "+strconv.Itoa(id))
+		return
+	}
+	if err != nil {
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	row.Close()
+	for i := range revisions {
+		next := post.Message
+		if i+1 < len(revisions) {
+			next = revisions[i+1].Message
+		}
+		revisions[i].Diff = diff.Lines(revisions[i].Message, next)
+	}
 
+	httputils.Respond(w, http.StatusOK, models.PostHistory{Post: post, Revisions: revisions})
+}
 
-	var result models.Thread
-	if isId == -1 {
-		err = tx.QueryRow( "selectThreadBySlug", thread).Scan(
-			&result.Id, &result.Title, &result.Author, &result.Forum, &result.Message, &result.Votes, &result.Slug, &result.Created)
-		if err != nil {
-			mes := models.Message{}
-			mes.Message = "Can't find thread by slug: " + thread
-			_ = tx.Rollback()
-			httputils.Respond(w, http.StatusNotFound, mes)
+// RollbackPost lets moderators bring back the text an earlier edit replaced.
+func (h *Handlers) RollbackPost(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		notFound(w, "Can't find post with id: "+params["id"])
+		return
+	}
+
+	var request struct {
+		Revision int `json:"revision"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	if h.auth.Enabled() {
+		existing, err := h.store.GetPost(r.Context(), id)
+		if store.IsNotFound(err, store.Post) {
+			notFound(w, "Can't find post with id: "+strconv.Itoa(id))
 			return
 		}
-	} else {
-		err = tx.QueryRow( "selectThreadById", isId).Scan(
-			&result.Id, &result.Title, &result.Author, &result.Forum, &result.Message, &result.Votes, &result.Slug, &result.Created)
 		if err != nil {
-			mes := models.Message{}
-			mes.Message = "Can't find thread by id: " + thread
-			_ = tx.Rollback()
-			httputils.Respond(w, http.StatusNotFound, mes)
+			httputils.Fail(w, r, err)
+			return
+		}
+		if !h.moderate(w, r, existing.Forum) {
 			return
 		}
 	}
 
-	vote.Thread = result.Id
+	post, err := h.store.RollbackPost(r.Context(), id, request.Revision, auth.Nickname(r.Context()))
+	if store.IsNotFound(err, store.Post) {
+		notFound(w, "Can't find post with id: "+strconv.Itoa(id))
+		return
+	}
+	if store.IsNotFound(err, store.Revision) {
+		notFound(w, "Can't find revision "+strconv.Itoa(request.Revision)+" of post: "+strconv.Itoa(id))
+		return
+	}
+	if err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	httputils.Respond(w, http.StatusOK, post)
+}
 
-	var vot int
-	_, err = tx.Exec("insertVote", vote.Thread, vote.Nickname, vote.Voice)
+// DeletePost leaves a tombstone in place of the post so that its replies
+// stay in the tree. With ?subtree=true a moderator removes the post together
+// with every reply under it.
+func (h *Handlers) DeletePost(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
 	if err != nil {
-		_ = tx.Rollback()
-		tx, err = h.conn.Begin()
+		notFound(w, "Can't find post with id: "+params["id"])
+		return
+	}
+	subtree := r.URL.Query().Get("subtree") == "true"
+
+	if h.auth.Enabled() {
+		existing, err := h.store.GetPost(r.Context(), id)
+		if store.IsNotFound(err, store.Post) {
+			notFound(w, "Can't find post with id: "+strconv.Itoa(id))
+			return
+		}
 		if err != nil {
-			httputils.Respond(w, http.StatusInternalServerError, nil)
+			httputils.Fail(w, r, err)
 			return
 		}
-
-		err = tx.QueryRow( "selectVote", vote.Thread, vote.Nickname).Scan(&vot)
-		if vot != vote.Voice {
-			_, err = tx.Exec("updateVote", vote.Thread, vote.Nickname, vote.Voice)
+		if subtree && !h.moderate(w, r, existing.Forum) {
+			return
+		}
+		if !subtree && !h.mayEdit(w, r, existing.Forum, existing.Author) {
+			return
 		}
 	}
 
-	if err != nil {
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+	var post models.Post
+	if subtree {
+		post, err = h.store.DeletePostTree(r.Context(), id)
+	} else {
+		post, err = h.store.DeletePost(r.Context(), id)
+	}
+	if store.IsNotFound(err, store.Post) {
+		notFound(w, "Can't find post with id: "+strconv.Itoa(id))
 		return
 	}
-
-	result.Votes = result.Votes - vot + vote.Voice
-
-	err = tx.Commit()
 	if err != nil {
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	httputils.Respond(w, http.StatusOK, result)
+	httputils.Respond(w, http.StatusOK, post)
 }
 
-func (h *Handlers) ThreadPosts(w http.ResponseWriter, r *http.Request) {
+// THREAD
+
+func (h *Handlers) CreatePost(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
-	thread := params["slug_or_id"]
+	thread := store.ParseThreadRef(params["slug_or_id"])
 
-	isId, err := strconv.Atoi(thread)
-	if err != nil {
-		isId = -1
+	var posts []models.Post
+
+	if err := json.NewDecoder(r.Body).Decode(&posts); err != nil {
+		httputils.Fail(w, r, err)
+		return
 	}
 
-	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
-	if err != nil {
-		limit = 100
+	for i := range posts {
+		bindNickname(r, &posts[i].Author)
+		if !h.actAs(w, r, posts[i].Author, auth.ScopePost) {
+			return
+		}
 	}
 
-	since, err := strconv.Atoi(r.URL.Query().Get("since"))
+	if len(posts) != 0 {
+		if t, err := h.store.GetThread(r.Context(), thread); err == nil {
+			checked := map[string]bool{}
+			for _, p := range posts {
+				if checked[strings.ToLower(p.Author)] {
+					continue
+				}
+				checked[strings.ToLower(p.Author)] = true
+				if !h.allowed(w, r, t.Forum, p.Author, acl.Post) {
+					return
+				}
+			}
+		} else if !store.IsNotFound(err, store.Thread) {
+			httputils.Fail(w, r, err)
+			return
+		}
+	}
+
+	posts, err := h.store.CreatePosts(r.Context(), thread, posts)
+	if store.IsNotFound(err, store.Thread) {
+		threadNotFound(w, "Can't find post thread", thread)
+		return
+	}
+	if errors.Is(err, store.ErrParentConflict) {
+		mes := models.Message{}
+		mes.Message = "Parent post was created in another thread"
+		httputils.Respond(w, http.StatusConflict, mes)
+		return
+	}
+	var missing *store.NotFoundError
+	if errors.As(err, &missing) && missing.What == store.User {
+		notFound(w, "Can't find post author by nickname: "+missing.Key)
+		return
+	}
 	if err != nil {
-		since = 0
+		httputils.Fail(w, r, err)
+		return
 	}
 
-	sort := r.URL.Query().Get("sort")
+	httputils.Respond(w, http.StatusCreated, posts)
+}
+
+func (h *Handlers) GetThread(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	thread := params["slug_or_id"]
 
-	desc, err := strconv.ParseBool(r.URL.Query().Get("desc"))
+	result, err := h.store.GetThread(r.Context(), store.ParseThreadRef(thread))
+	if store.IsNotFound(err, store.Thread) {
+		notFound(w, "Can't find thread by slug or id: "+thread)
+		return
+	}
 	if err != nil {
-		desc = false
+		httputils.Fail(w, r, err)
+		return
 	}
 
-	tx, err := h.conn.Begin()
-	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+	httputils.Respond(w, http.StatusOK, result)
+}
+
+func (h *Handlers) ChangeThread(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	thread := store.ParseThreadRef(params["slug_or_id"])
+
+	var update models.Thread
+
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	var id int
-	if isId != -1 {
-		id = isId
-		err := tx.QueryRow("selectIdThreadById", isId).Scan(&id)
-		if err != nil {
-			mes := models.Message{}
-			mes.Message = "Can't find thread by id: " + thread
-			_ = tx.Rollback()
-			httputils.Respond(w, http.StatusNotFound, mes)
+	if h.auth.Enabled() {
+		existing, err := h.store.GetThread(r.Context(), thread)
+		if store.IsNotFound(err, store.Thread) {
+			threadNotFound(w, "Can't find thread", thread)
 			return
 		}
-	} else {
-		err = tx.QueryRow( "selectIdThreadBySlug", thread).Scan(&id)
 		if err != nil {
-			mes := models.Message{}
-			mes.Message = "Can't find thread by slug: " + thread
-			_ = tx.Rollback()
-			httputils.Respond(w, http.StatusNotFound, mes)
+			httputils.Fail(w, r, err)
 			return
 		}
-	}
-
-	var posts []models.Post
-	var row *pgx.Rows
-
-	// Float
-	switch sort {
-	case "tree":
-		if since == 0 {
-			if desc {
-				row, err = tx.Query(
-					"treeDesc",
-							id, limit)
-			} else {
-				row, err = tx.Query(
-					"tree",
-					id, limit)
-			}
-		} else {
-			if desc {
-				row, err = tx.Query(
-					"treeDescSince",
-					id, limit, since)
-			} else {
-				row, err = tx.Query(
-					"treeSince",
-					id, limit, since)
-			}
-		}
-	case "parent_tree":
-		if since == 0 {
-			if desc {
-				row, err = tx.Query(
-					"parentTreeDesc",
-							id, limit)
-			} else {
-				row, err = tx.Query(
-					"parentTree",
-							id, limit)
-			}
-		} else {
-			if desc {
-				row, err = tx.Query(
-					"parentTreeDescSince",
-							id, limit, since)
-			} else {
-				row, err = tx.Query(
-					"parentTreeSince",
-					id, limit, since)
-			}
-		}
-	default:
-		if since == 0 {
-			if desc {
-				row, err = tx.Query(
-					"flatDesc",
-					id,
-					limit,
-				)
-			} else {
-				row, err = tx.Query(
-					"flat",
-					id,
-					limit,
-				)
-			}
-		} else {
-			if desc {
-				row, err = tx.Query(
-					"flatDescSince",
-					id,
-					limit,
-					since,
-				)
-			} else {
-				row, err = tx.Query(
-					"flatSince",
-					id,
-					limit,
-					since,
-				)
-			}
+		if !h.mayEdit(w, r, existing.Forum, existing.Author) {
+			return
 		}
 	}
 
+	result, err := h.store.UpdateThread(r.Context(), thread, update)
+	if store.IsNotFound(err, store.Thread) {
+		threadNotFound(w, "Can't find thread", thread)
+		return
+	}
 	if err != nil {
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusInternalServerError, nil)
-		return
-	}
-
-	for row.Next() {
-		p := models.Post{}
-		err = row.Scan(
-			&p.Id,
-			&p.Author,
-			&p.Created,
-			&p.Forum,
-			&p.IsEdited,
-			&p.Message,
-			&p.Parent,
-			&p.Thread)
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	httputils.Respond(w, http.StatusOK, result)
+}
+
+// DeleteThread removes the thread with its posts and votes, or with
+// ?soft=true only hides it until RestoreThread.
+func (h *Handlers) DeleteThread(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	thread := store.ParseThreadRef(params["slug_or_id"])
+	soft := r.URL.Query().Get("soft") == "true"
+
+	h.moderateThread(w, r, thread, func() (models.Thread, error) {
+		return h.store.DeleteThread(r.Context(), thread, soft)
+	})
+}
+
+func (h *Handlers) RestoreThread(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	thread := store.ParseThreadRef(params["slug_or_id"])
+
+	h.moderateThread(w, r, thread, func() (models.Thread, error) {
+		return h.store.RestoreThread(r.Context(), thread)
+	})
+}
+
+// moderateThread checks that the caller moderates the forum of the thread,
+// soft-deleted ones included, and responds with the result of action.
+func (h *Handlers) moderateThread(w http.ResponseWriter, r *http.Request, thread store.ThreadRef, action func() (models.Thread, error)) {
+	if h.auth.Enabled() {
+		forum, err := h.store.ThreadForum(r.Context(), thread)
+		if store.IsNotFound(err, store.Thread) {
+			threadNotFound(w, "Can't find thread", thread)
+			return
+		}
 		if err != nil {
-			_ = tx.Rollback()
-			httputils.Respond(w, http.StatusInternalServerError, nil)
+			httputils.Fail(w, r, err)
+			return
+		}
+		if !h.moderate(w, r, forum) {
 			return
 		}
-
-		posts = append(posts, p)
 	}
 
-	if posts == nil {
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusOK, []models.Post{})
+	result, err := action()
+	if store.IsNotFound(err, store.Thread) {
+		threadNotFound(w, "Can't find thread", thread)
 		return
 	}
-
-	err = tx.Commit()
 	if err != nil {
-		_ = tx.Rollback()
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	httputils.Respond(w, http.StatusOK, posts)
+	httputils.Respond(w, http.StatusOK, result)
 }
 
-// SERVICE
+func (h *Handlers) CreateVote(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	thread := store.ParseThreadRef(params["slug_or_id"])
 
-func (h *Handlers) AllClear(w http.ResponseWriter, r *http.Request) {
-	tx, err := h.conn.Begin()
-	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
+	var vote models.Vote
+
+	if err := json.NewDecoder(r.Body).Decode(&vote); err != nil {
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	_, err = tx.Exec("delForum")
-	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
-		_ = tx.Rollback()
+	bindNickname(r, &vote.Nickname)
+	if !h.actAs(w, r, vote.Nickname, auth.ScopeVote) {
 		return
 	}
-	_, err = tx.Exec("delPost")
-	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
-		_ = tx.Rollback()
+
+	if t, err := h.store.GetThread(r.Context(), thread); err == nil {
+		if !h.allowed(w, r, t.Forum, vote.Nickname, acl.Vote) {
+			return
+		}
+	} else if !store.IsNotFound(err, store.Thread) {
+		httputils.Fail(w, r, err)
 		return
 	}
-	_, err = tx.Exec("delThread")
-	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
-		_ = tx.Rollback()
+
+	result, err := h.store.Vote(r.Context(), thread, vote)
+	if store.IsNotFound(err, store.User) {
+		notFound(w, "Can't find user by nickname: "+vote.Nickname)
 		return
 	}
-	_, err = tx.Exec("delUser")
-	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
-		_ = tx.Rollback()
+	if store.IsNotFound(err, store.Thread) {
+		threadNotFound(w, "Can't find thread", thread)
 		return
 	}
-
-	_, err = tx.Exec("delVote")
 	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
-		_ = tx.Rollback()
+		httputils.Fail(w, r, err)
 		return
 	}
 
-	_, err = tx.Exec("delForumUsers")
-	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
-		_ = tx.Rollback()
+	httputils.Respond(w, http.StatusOK, result)
+}
+
+func (h *Handlers) ThreadPosts(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	thread := store.ParseThreadRef(params["slug_or_id"])
+
+	limit, ok := queryLimit(w, r)
+	if !ok {
 		return
 	}
 
-	err = tx.Commit()
+	since, err := strconv.Atoi(r.URL.Query().Get("since"))
 	if err != nil {
-		httputils.Respond(w, http.StatusInternalServerError, nil)
-		_ = tx.Rollback()
-		return
+		since = 0
 	}
 
-	httputils.Respond(w, http.StatusOK, nil)
-}
-
-func (h *Handlers) AllInfo(w http.ResponseWriter, r *http.Request) {
-	var status models.Status
-
-	err := h.conn.QueryRow("countUser").Scan(&status.User)
+	desc, err := strconv.ParseBool(r.URL.Query().Get("desc"))
 	if err != nil {
-		status.User = 0
+		desc = false
 	}
-	err = h.conn.QueryRow("countForum").Scan(&status.Forum)
-	if err != nil {
-		status.Forum = 0
+
+	posts, err := h.store.ThreadPosts(r.Context(), thread, store.PostsQuery{
+		Limit: limit,
+		Since: since,
+		Sort:  r.URL.Query().Get("sort"),
+		Desc:  desc,
+	})
+	if store.IsNotFound(err, store.Thread) {
+		threadNotFound(w, "Can't find thread", thread)
+		return
 	}
-	err = h.conn.QueryRow("countThread").Scan(&status.Thread)
 	if err != nil {
-		status.Thread = 0
+		httputils.Fail(w, r, err)
+		return
 	}
-	err = h.conn.QueryRow("countPost").Scan(&status.Post)
+
+	httputils.Respond(w, http.StatusOK, posts)
+}
+
+// SERVICE
+
+func (h *Handlers) AllInfo(w http.ResponseWriter, r *http.Request) {
+	status, err := h.store.Status(r.Context())
 	if err != nil {
-		status.Post = 0
+		httputils.Fail(w, r, err)
+		return
 	}
 
 	httputils.Respond(w, http.StatusOK, status)
 }
-
-// PREPARE
-
-func (h *Handlers) Prepare() {
-	_, _ = h.conn.Prepare("insertVote", "INSERT INTO forum.vote(thread, nickname, voice) VALUES ($1, $2, $3)")
-	_, _ = h.conn.Prepare("updateVote", "UPDATE forum.vote SET voice = $3 WHERE thread = $1 and nickname = $2")
-	_, _ = h.conn.Prepare("selectVote", "SELECT voice FROM forum.vote WHERE thread = $1 and nickname = $2 LIMIT 1")
-
-
-	_, _ = h.conn.Prepare("insertUser", "INSERT INTO forum.\"user\"(nickname, fullname, about, email) VALUES ($1, $2, $3, $4)")
-	_, _ = h.conn.Prepare("selectDublicateUser", "SELECT nickname, fullname, about, email FROM forum.\"user\" WHERE nickname = $1 OR email = $2 LIMIT 2")
-	_, _ = h.conn.Prepare("selectUser", "SELECT nickname, fullname, about, email FROM forum.\"user\" WHERE nickname = $1 LIMIT 1")
-	_, _ = h.conn.Prepare("checkUser", "SELECT nickname FROM forum.\"user\" WHERE nickname = $1 LIMIT 1")
-	_, _ = h.conn.Prepare("changeUser", "UPDATE forum.\"user\" \n\t\t\t   SET fullname = COALESCE(NULLIF($1, ''), fullname),\n\t\t\t       about = COALESCE(NULLIF($2, ''), about),\n\t\t\t       email = COALESCE(NULLIF($3, ''), email) \n\t\t\t   WHERE nickname = $4 \n\t\t\t   RETURNING nickname, fullname, about, email")
-	_, _ = h.conn.Prepare("selectUserOrderDesc", "select nickname, fullname, about, email\n\t\t\t\t\t\tfrom forum.forum_users\n\t\t\t\t\t\tWHERE forum = $1\n\t\t\t\t\t\torder by nickname desc\n\t\t\t\t\tlimit $2")
-	_, _ = h.conn.Prepare("selectUserOrder", "select nickname, fullname, about, email\n\t\t\t\t\t\tfrom forum.forum_users\n\t\t\t\t\t\tWHERE forum = $1\n\t\t\t\t\t\torder by nickname\n\t\t\t\t\tlimit $2")
-	_, _ = h.conn.Prepare("selectUserWhereOrderDesc", "select nickname, fullname, about, email\n\t\t\t\t\t\tfrom forum.forum_users\n\t\t\t\t\t\tWHERE forum = $1 and nickname < $3\n\t\t\t\t\t\torder by nickname desc\n\t\t\t\t\tlimit $2")
-	_, _ = h.conn.Prepare("selectUserWhereOrder", "select nickname, fullname, about, email\n\t\t\t\t\t\tfrom forum.forum_users\n\t\t\t\t\t\tWHERE forum = $1 and nickname > $3\n\t\t\t\t\t\torder by nickname\n\t\t\t\t\tlimit $2")
-
-
-	_, _ = h.conn.Prepare("insertForum", "INSERT INTO forum.forum(title, \"user\", slug)\n\t\t\t   VALUES ($1, $2, $3)")
-	_, _ = h.conn.Prepare("selectForum", "SELECT title, \"user\", slug, posts, threads FROM forum.forum WHERE slug = $1 LIMIT 1")
-	_, _ = h.conn.Prepare("checkForum", "SELECT slug FROM forum.forum WHERE slug = $1 LIMIT 1")
-
-
-	_, _ = h.conn.Prepare("insertThread", "INSERT INTO forum.thread(title, author, forum, message, votes, slug, created)\n\t\tVALUES ($1, $2, $3, $4, $5, nullif($6, ''), $7)\n\t\tRETURNING id")
-	_, _ = h.conn.Prepare("selectThread", "SELECT id, title, author, forum, message, votes, slug, created\n\t\t\t\t\tFROM forum.thread\n\t\t\t\t\tWHERE slug = $1 LIMIT 1")
-	_, _ = h.conn.Prepare("selectThreadById", "SELECT id, title, author, forum, message, votes, coalesce(slug, '') as slug, created FROM forum.thread WHERE id = $1 LIMIT 1")
-	_, _ = h.conn.Prepare("selectThreadOrderDesc", "select t.id, t.title, t.author, t.forum, t.message, t.votes, coalesce(t.slug, '') as slug, t.created\n\t\t\t\t\t\tfrom forum.thread t\n\t\t\t\t\t\twhere t.forum = $1\n\t\t\t\t\t\torder by t.created desc\n\t\t\t\t\t\tlimit $2")
-	_, _ = h.conn.Prepare("selectThreadOrder", "select t.id, t.title, t.author, t.forum, t.message, t.votes, coalesce(t.slug, '') as slug, t.created\n\t\t\t\t\t\tfrom forum.thread t\n\t\t\t\t\t\twhere t.forum = $1\n\t\t\t\t\t\torder by t.created\n\t\t\t\t\t\tlimit $2")
-	_, _ = h.conn.Prepare("selectThreadWhereOrderDesc", "select t.id, t.title, t.author, t.forum, t.message, t.votes, coalesce(t.slug, '') as slug, t.created\n\t\t\t\t\t\tfrom forum.thread t\n\t\t\t\t\t\twhere t.forum = $1 and t.created <= $3\n\t\t\t\t\t\torder by t.created desc\n\t\t\t\t\t\tlimit $2")
-	_, _ = h.conn.Prepare("selectThreadWhereOrder", "select t.id, t.title, t.author, t.forum, t.message, t.votes, coalesce(t.slug, '') as slug, t.created\n\t\t\t\t\t\tfrom forum.thread t\n\t\t\t\t\t\twhere t.forum = $1 and t.created >= $3\n\t\t\t\t\t\torder by t.created\n\t\t\t\t\t\tlimit $2")
-	_, _ = h.conn.Prepare("selectIdForumThreadBySlug", "SELECT id, forum FROM forum.thread WHERE slug = $1 LIMIT 1")
-	_, _ = h.conn.Prepare("selectIdForumThreadById", "SELECT id, forum FROM forum.thread WHERE id = $1 LIMIT 1")
-	_, _ = h.conn.Prepare("selectThreadBySlug", "SELECT id, title, author, forum, message, votes, coalesce(slug, ''), created FROM forum.thread WHERE slug = $1 LIMIT 1")
-	_, _ = h.conn.Prepare("updateThreadBySlug", "UPDATE forum.thread SET title = COALESCE(nullif($1, ''), title), message = COALESCE(nullif($2, ''), message) WHERE slug = $3 RETURNING *")
-	_, _ = h.conn.Prepare("updateThreadById", "UPDATE forum.thread SET title = COALESCE(nullif($1, ''), title), message = COALESCE(nullif($2, ''), message) WHERE id = $3 RETURNING *")
-	_, _ = h.conn.Prepare("selectIdThreadById", "SELECT id as thread FROM forum.thread WHERE id = $1 LIMIT 1")
-	_, _ = h.conn.Prepare("selectIdThreadBySlug", "SELECT id as thread FROM forum.thread WHERE slug = $1 LIMIT 1")
-
-
-	_, _ = h.conn.Prepare("selectPost", "SELECT id, parent, author, message, isEdited, forum, thread, created FROM forum.post WHERE id = $1 LIMIT 1")
-	_, _ = h.conn.Prepare("updatePost", "UPDATE forum.post\n\t\t\t\tSET message = COALESCE(nullif($1, ''), message), isEdited = CASE $1 WHEN message THEN false WHEN '' THEN false ELSE true end\n\t\t\t\tWHERE id = $2\n\t\t\t\tRETURNING id, parent, author, message, isEdited, forum, thread, created ")
-	_, _ = h.conn.Prepare("selectThreadIdFromPost", "SELECT thread FROM forum.post WHERE id = $1")
-	_, _ = h.conn.Prepare("treeDesc", "SELECT id, author, created, forum, isEdited, message, parent, thread\n\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\tWHERE thread = $1\n\t\t\t\t\t\t\tORDER BY path DESC, id DESC\n\t\t\t\t\t\t\tLIMIT $2")
-	_, _ = h.conn.Prepare("tree", "SELECT id, author, created, forum, isEdited, message, parent, thread\n\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\tWHERE thread = $1\n\t\t\t\t\t\t\tORDER BY path, id\n\t\t\t\t\t\t\tLIMIT $2")
-	_, _ = h.conn.Prepare("treeDescSince", "SELECT id, author, created, forum, isEdited, message, parent, thread\n\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\tWHERE thread = $1 and path < (SELECT path FROM forum.post WHERE id = $3 LIMIT 1)\n\t\t\t\t\t\t\tORDER BY path DESC, id DESC\n\t\t\t\t\t\t\tLIMIT $2")
-	_, _ = h.conn.Prepare("treeSince", "SELECT id, author, created, forum, isEdited, message, parent, thread\n\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\tWHERE thread = $1 and path > (SELECT path FROM forum.post WHERE id = $3 LIMIT 1)\n\t\t\t\t\t\t\tORDER BY path, id\n\t\t\t\t\t\t\tLIMIT $2")
-	_, _ = h.conn.Prepare("parentTreeDesc", "SELECT id, author, created, forum, isEdited, message, parent, thread\n\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\tWHERE path[1] IN (\n\t\t\t\t\t\t\t\tSELECT id\n\t\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\t\tWHERE thread = $1 and parent = 0\n\t\t\t\t\t\t\t\tORDER BY id DESC\n\t\t\t\t\t\t\t\tLIMIT $2)\n\t\t\t\t\t\t\tORDER BY path[1] DESC, path, id")
-	_, _ = h.conn.Prepare("parentTree", "SELECT id, author, created, forum, isEdited, message, parent, thread\n\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\tWHERE path[1] IN (\n\t\t\t\t\t\t\t\tSELECT id\n\t\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\t\tWHERE thread = $1 AND parent = 0\n\t\t\t\t\t\t\t\tORDER BY id\n\t\t\t\t\t\t\t\tLIMIT $2)\n\t\t\t\t\t\t\tORDER BY path")
-	_, _ = h.conn.Prepare("parentTreeDescSince", "SELECT id, author, created, forum, isEdited, message, parent, thread\n\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\tWHERE path[1] IN (\n\t\t\t\t\t\t\t\tSELECT id\n\t\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\t\tWHERE thread = $1 AND parent = 0 and path[1] < (SELECT path[1] FROM forum.post WHERE id = $3 LIMIT 1)\n\t\t\t\t\t\t\t\tORDER BY id DESC\n\t\t\t\t\t\t\t\tLIMIT $2)\n\t\t\t\t\t\t\tORDER BY path[1] DESC, path, id")
-	_, _ = h.conn.Prepare("parentTreeSince", "SELECT id, author, created, forum, isEdited, message, parent, thread\n\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\tWHERE path[1] in (\n\t\t\t\t\t\t\t\tSELECT id\n\t\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\t\tWHERE thread = $1 AND parent = 0 and path[1] > (SELECT path[1] FROM forum.post WHERE id = $3 LIMIT 1)\n\t\t\t\t\t\t\t\tORDER BY id ASC\n\t\t\t\t\t\t\t\tLIMIT $2)\n\t\t\t\t\t\t\tORDER BY path, id")
-	_, _ = h.conn.Prepare("flatDesc", "SELECT id, author, created, forum, isEdited, message, parent, thread\n\t\t\t\t\t   FROM forum.post\n\t\t\t\t\t   WHERE thread = $1\n\t\t\t\t\t   ORDER BY id DESC\n\t\t\t\t\t   LIMIT $2")
-	_, _ = h.conn.Prepare("flat", "SELECT id, author, created, forum, isEdited, message, parent, thread\n\t\t\t\t\t   FROM forum.post\n\t\t\t\t\t   WHERE thread = $1\n\t\t\t\t\t   ORDER BY id\n\t\t\t\t\t   LIMIT $2")
-	_, _ = h.conn.Prepare("flatDescSince", "SELECT id, author, created, forum, isEdited, message, parent, thread\n\t\t\t\t\t   FROM forum.post\n\t\t\t\t\t   WHERE thread = $1 and id < $3\n\t\t\t\t\t   ORDER BY id DESC\n\t\t\t\t\t   LIMIT $2")
-	_, _ = h.conn.Prepare("flatSince", "SELECT id, author, created, forum, isEdited, message, parent, thread\n\t\t\t\t\t   FROM forum.post\n\t\t\t\t\t   WHERE thread = $1 and id > $3\n\t\t\t\t\t   ORDER BY id\n\t\t\t\t\t   LIMIT $2")
-
-
-	_, _ = h.conn.Prepare("delForum", "TRUNCATE forum.forum CASCADE")
-	_, _ = h.conn.Prepare("delPost", "TRUNCATE forum.post CASCADE")
-	_, _ = h.conn.Prepare("delThread", "TRUNCATE forum.thread CASCADE")
-	_, _ = h.conn.Prepare("delUser", "TRUNCATE forum.\"user\" CASCADE")
-	_, _ = h.conn.Prepare("delVote", "TRUNCATE forum.vote CASCADE")
-	_, _ = h.conn.Prepare("delForumUsers", "TRUNCATE forum.forum_users CASCADE")
-	_, _ = h.conn.Prepare("countUser", "SELECT COUNT(*) FROM forum.\"user\"")
-	_, _ = h.conn.Prepare("countForum", "SELECT COUNT(*) FROM forum.forum")
-	_, _ = h.conn.Prepare("countThread", "SELECT COUNT(*) FROM forum.thread")
-	_, _ = h.conn.Prepare("countPost", "SELECT COUNT(*) FROM forum.post")
-}
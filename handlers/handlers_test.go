@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"server/auth"
+	"server/config"
+	"server/models"
+	"server/store"
+	"server/store/memory"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type testServer struct {
+	t     *testing.T
+	store *memory.Store
+	auth  *auth.Auth
+	h     *Handlers
+}
+
+func newTestServer(t *testing.T, authEnabled bool) *testServer {
+	s := memory.New()
+	a := auth.New(s, config.Auth{Enabled: authEnabled, TokenTTL: time.Hour})
+	return &testServer{t: t, store: s, auth: a, h: NewHandler(s, a, "admin-token")}
+}
+
+// user creates a user with the password "secret".
+func (ts *testServer) user(nickname string) {
+	ts.t.Helper()
+	hash, err := auth.HashPassword("secret")
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	user := models.User{Nickname: nickname, Fullname: nickname, Email: nickname + "@example.com"}
+	if _, err := ts.store.CreateUser(context.Background(), user, hash); err != nil {
+		ts.t.Fatal(err)
+	}
+}
+
+// login returns a session token for the user.
+func (ts *testServer) login(nickname string) string {
+	ts.t.Helper()
+	token, err := ts.auth.Login(context.Background(), nickname, "secret")
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	return token.Token
+}
+
+// do serves one request through the auth middleware, as the router would,
+// with vars as the route variables. An empty token sends no Authorization.
+func (ts *testServer) do(handler http.HandlerFunc, method, token string, vars map[string]string, body interface{}) *httptest.ResponseRecorder {
+	ts.t.Helper()
+	return ts.serve(handler, ts.request(method, token, vars, body))
+}
+
+// request builds what do sends, for tests that need to add to it.
+func (ts *testServer) request(method, token string, vars map[string]string, body interface{}) *http.Request {
+	ts.t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	r := httptest.NewRequest(method, "/", strings.NewReader(string(data)))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return mux.SetURLVars(r, vars)
+}
+
+func (ts *testServer) serve(handler http.HandlerFunc, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	ts.auth.Middleware(handler).ServeHTTP(w, r)
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %q: %v", w.Body.String(), err)
+	}
+}
+
+func TestChangeUserKeepsPathNickname(t *testing.T) {
+	ts := newTestServer(t, true)
+	ts.user("alice")
+	ts.user("bob")
+
+	body := models.User{Nickname: "alice", Fullname: "Mallory"}
+	w := ts.do(ts.h.ChangeUser, http.MethodPost, ts.login("bob"), map[string]string{"nickname": "bob"}, body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want 200: %s", w.Code, w.Body)
+	}
+
+	var changed models.User
+	decode(t, w, &changed)
+	if changed.Nickname != "bob" || changed.Fullname != "Mallory" {
+		t.Errorf("response: got %+v, want bob renamed", changed)
+	}
+
+	alice, err := ts.store.GetUser(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice.Fullname != "alice" {
+		t.Errorf("alice was changed through bob's profile: %+v", alice)
+	}
+}
+
+// post creates a forum owned by author with one thread and returns the id of
+// the first post in it.
+func (ts *testServer) post(forum, author, message string) int {
+	ts.t.Helper()
+	ctx := context.Background()
+	if _, err := ts.store.CreateForum(ctx, models.Forum{Slug: forum, Title: forum, User: author}); err != nil {
+		ts.t.Fatal(err)
+	}
+	thread, err := ts.store.CreateThread(ctx, models.Thread{Forum: forum, Author: author, Title: forum, Message: forum})
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	posts, err := ts.store.CreatePosts(ctx, store.ThreadRef{ID: thread.Id}, []models.Post{{Author: author, Message: message}})
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	return posts[0].Id
+}
+
+func TestPostHistoryRollback(t *testing.T) {
+	ts := newTestServer(t, true)
+	ts.user("alice")
+	token := ts.login("alice")
+	id := ts.post("pirates", "alice", "one\ntwo")
+	vars := map[string]string{"id": strconv.Itoa(id)}
+
+	for _, message := range []string{"one\n2", "one\n2\nthree"} {
+		w := ts.do(ts.h.ChangePost, http.MethodPost, token, vars, models.Post{Message: message})
+		if w.Code != http.StatusOK {
+			t.Fatalf("edit: status %d: %s", w.Code, w.Body)
+		}
+	}
+
+	var history models.PostHistory
+	w := ts.do(ts.h.PostHistory, http.MethodGet, token, vars, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("history: status %d: %s", w.Code, w.Body)
+	}
+	decode(t, w, &history)
+	if len(history.Revisions) != 2 {
+		t.Fatalf("history: got %d revisions, want 2", len(history.Revisions))
+	}
+	wantDiffs := [][]string{
+		{"  one", "- two", "+ 2"},
+		{"  one", "  2", "+ three"},
+	}
+	for i, rev := range history.Revisions {
+		if !reflect.DeepEqual(rev.Diff, wantDiffs[i]) {
+			t.Errorf("revision %d diff: got %q, want %q", i, rev.Diff, wantDiffs[i])
+		}
+		if rev.Editor != "alice" {
+			t.Errorf("revision %d editor: got %q, want alice", i, rev.Editor)
+		}
+	}
+
+	body := map[string]int{"revision": history.Revisions[0].Id}
+	w = ts.do(ts.h.RollbackPost, http.MethodPost, token, vars, body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("rollback: status %d: %s", w.Code, w.Body)
+	}
+	var post models.Post
+	decode(t, w, &post)
+	if post.Message != "one\ntwo" || !post.IsEdited {
+		t.Errorf("rollback: got %+v, want the first message, edited", post)
+	}
+
+	w = ts.do(ts.h.PostHistory, http.MethodGet, token, vars, nil)
+	decode(t, w, &history)
+	if n := len(history.Revisions); n != 3 || history.Revisions[n-1].Message != "one\n2\nthree" {
+		t.Errorf("history after rollback: got %+v, want the replaced text as a third revision", history.Revisions)
+	}
+
+	body = map[string]int{"revision": 999}
+	if w := ts.do(ts.h.RollbackPost, http.MethodPost, token, vars, body); w.Code != http.StatusNotFound {
+		t.Errorf("rollback to a missing revision: got %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"server/database"
+	"server/httputils"
+	"server/models"
+	"sync/atomic"
+	"time"
+)
+
+const readyTimeout = 2 * time.Second
+
+type Health struct {
+	postgres *database.Postgres
+	draining int32
+}
+
+// NewHealth checks the database, schema and statements of postgres, which is
+// nil when the API runs on the memory store.
+func NewHealth(postgres *database.Postgres) *Health {
+	return &Health{
+		postgres: postgres,
+	}
+}
+
+// Drain makes Ready fail so that traffic moves away during shutdown.
+func (h *Health) Drain() {
+	atomic.StoreInt32(&h.draining, 1)
+}
+
+func (h *Health) Live(w http.ResponseWriter, r *http.Request) {
+	httputils.Respond(w, http.StatusOK, models.Health{Status: "ok"})
+}
+
+func (h *Health) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	result := models.Health{Status: "ok", Checks: map[string]models.HealthCheck{}}
+	check := func(name string, f func() error) {
+		start := time.Now()
+		c := models.HealthCheck{Status: "ok"}
+		if err := f(); err != nil {
+			c.Status = "fail"
+			c.Error = err.Error()
+			result.Status = "fail"
+		}
+		c.Duration = time.Since(start).String()
+		result.Checks[name] = c
+	}
+
+	if atomic.LoadInt32(&h.draining) == 1 {
+		result.Status = "fail"
+		result.Checks["shutdown"] = models.HealthCheck{Status: "fail", Error: "server is shutting down"}
+	}
+
+	if h.postgres == nil {
+		check("storage", func() error { return nil })
+		httputils.Respond(w, readyStatus(result), result)
+		return
+	}
+
+	check("database", func() error {
+		return h.postgres.Ping(ctx)
+	})
+
+	check("schema", func() error {
+		expected, err := database.LatestVersion()
+		if err != nil {
+			return err
+		}
+		current, err := h.postgres.CurrentVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if current != expected {
+			return fmt.Errorf("schema version %d, expected %d", current, expected)
+		}
+		return nil
+	})
+
+	check("statements", func() error {
+		if !h.postgres.Statements().Ready() {
+			failed := 0
+			for _, st := range h.postgres.Statements().List() {
+				if !st.Prepared {
+					failed++
+				}
+			}
+			return fmt.Errorf("%d statements are not prepared", failed)
+		}
+		return nil
+	})
+
+	httputils.Respond(w, readyStatus(result), result)
+}
+
+func readyStatus(result models.Health) int {
+	if result.Status != "ok" {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
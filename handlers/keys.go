@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"server/auth"
+	"server/httputils"
+	"server/models"
+	"server/store"
+	"strconv"
+	"time"
+)
+
+func (h *Handlers) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	nickname := params["nickname"]
+
+	key := models.APIKey{}
+
+	if err := json.NewDecoder(r.Body).Decode(&key); err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+	key.Nickname = nickname
+
+	mes := models.Message{}
+	if len(key.Scopes) == 0 {
+		mes.Message = "At least one scope is required"
+	}
+	for _, scope := range key.Scopes {
+		if !auth.ValidScope(scope) {
+			mes.Message = "Unknown scope: " + scope + ", use read, post, vote, moderate or admin"
+		}
+	}
+	if key.Expires != nil && !key.Expires.After(time.Now()) {
+		mes.Message = "Expiry must be in the future"
+	}
+	if mes.Message != "" {
+		httputils.Respond(w, http.StatusBadRequest, mes)
+		return
+	}
+
+	if !h.actAs(w, r, nickname, auth.ScopeAdmin) {
+		return
+	}
+
+	key, err := h.auth.CreateKey(r.Context(), key)
+	if store.IsNotFound(err, store.User) {
+		notFound(w, "Can't find user by nickname: "+nickname)
+		return
+	}
+	if err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	httputils.Respond(w, http.StatusCreated, key)
+}
+
+func (h *Handlers) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	nickname := params["nickname"]
+
+	if !h.actAs(w, r, nickname, auth.ScopeAdmin) {
+		return
+	}
+
+	keys, err := h.store.APIKeys(r.Context(), nickname)
+	if store.IsNotFound(err, store.User) {
+		notFound(w, "Can't find user by nickname: "+nickname)
+		return
+	}
+	if err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	httputils.Respond(w, http.StatusOK, keys)
+}
+
+func (h *Handlers) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	nickname := params["nickname"]
+
+	if !h.actAs(w, r, nickname, auth.ScopeAdmin) {
+		return
+	}
+
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		notFound(w, "Can't find API key with id: "+params["id"])
+		return
+	}
+
+	key, err := h.store.RevokeAPIKey(r.Context(), nickname, id)
+	if store.IsNotFound(err, store.APIKey) {
+		notFound(w, "Can't find API key with id: "+params["id"])
+		return
+	}
+	if err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	httputils.Respond(w, http.StatusOK, key)
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"server/acl"
+	"server/auth"
+	"server/httputils"
+	"server/models"
+	"server/store"
+	"strings"
+)
+
+func (h *Handlers) GetForumRoles(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	slug := params["slug"]
+
+	forum, err := h.store.GetForum(r.Context(), slug)
+	if store.IsNotFound(err, store.Forum) {
+		notFound(w, "Can't find forum by slug: "+slug)
+		return
+	}
+	if err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	roles, err := h.store.ForumRoles(r.Context(), forum.Slug)
+	if err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	roles = append([]models.ForumRole{{Forum: forum.Slug, Nickname: forum.User, Role: acl.Owner}}, roles...)
+	httputils.Respond(w, http.StatusOK, roles)
+}
+
+func (h *Handlers) SetForumRole(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	role := models.ForumRole{}
+
+	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+	role.Forum = params["slug"]
+
+	if !acl.ValidRole(role.Role) {
+		mes := models.Message{}
+		mes.Message = "Unknown role: " + role.Role + ", use owner, moderator, member or banned"
+		httputils.Respond(w, http.StatusBadRequest, mes)
+		return
+	}
+
+	if !h.manageRoles(w, r, role.Forum, role.Nickname) {
+		return
+	}
+
+	result, err := h.store.SetForumRole(r.Context(), role)
+	if store.IsNotFound(err, store.User) {
+		notFound(w, "Can't find user by nickname: "+role.Nickname)
+		return
+	}
+	if err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	httputils.Respond(w, http.StatusOK, result)
+}
+
+func (h *Handlers) DeleteForumRole(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	slug := params["slug"]
+	nickname := params["nickname"]
+
+	if !h.manageRoles(w, r, slug, nickname) {
+		return
+	}
+
+	role, err := h.store.DeleteForumRole(r.Context(), slug, nickname)
+	if store.IsNotFound(err, store.Role) {
+		notFound(w, "Can't find role of user "+nickname+" in forum: "+slug)
+		return
+	}
+	if store.IsNotFound(err, store.Forum) {
+		notFound(w, "Can't find forum by slug: "+slug)
+		return
+	}
+	if err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	httputils.Respond(w, http.StatusOK, role)
+}
+
+// manageRoles checks that the forum exists, that the creator's role is left
+// alone and, with authentication enabled, that the caller may manage users.
+func (h *Handlers) manageRoles(w http.ResponseWriter, r *http.Request, slug, nickname string) bool {
+	forum, err := h.store.GetForum(r.Context(), slug)
+	if store.IsNotFound(err, store.Forum) {
+		notFound(w, "Can't find forum by slug: "+slug)
+		return false
+	}
+	if err != nil {
+		httputils.Fail(w, r, err)
+		return false
+	}
+
+	if strings.EqualFold(forum.User, nickname) {
+		mes := models.Message{}
+		mes.Message = "Can't change the role of the forum creator: " + forum.User
+		httputils.Respond(w, http.StatusConflict, mes)
+		return false
+	}
+
+	if !h.auth.Enabled() {
+		return true
+	}
+	who := auth.Nickname(r.Context())
+	if who == "" {
+		auth.Unauthorized(w, "Authentication required")
+		return false
+	}
+	if !auth.HasScope(r.Context(), auth.ScopeModerate) {
+		auth.MissingScope(w, auth.ScopeModerate)
+		return false
+	}
+	return h.allowed(w, r, forum.Slug, who, acl.ManageUsers)
+}
+
+// manageForum lets only the owner change or delete a forum when
+// authentication is enabled.
+func (h *Handlers) manageForum(w http.ResponseWriter, r *http.Request, slug string) bool {
+	if !h.auth.Enabled() {
+		return true
+	}
+	who := auth.Nickname(r.Context())
+	if who == "" {
+		auth.Unauthorized(w, "Authentication required")
+		return false
+	}
+	if !auth.HasScope(r.Context(), auth.ScopeAdmin) {
+		auth.MissingScope(w, auth.ScopeAdmin)
+		return false
+	}
+	return h.allowed(w, r, slug, who, acl.ManageUsers)
+}
+
+// ownForum is manageForum for a soft-deleted forum, which has no roles left
+// to check: only its owner may act on it.
+func (h *Handlers) ownForum(w http.ResponseWriter, r *http.Request, forum models.Forum) bool {
+	if !h.auth.Enabled() {
+		return true
+	}
+	who := auth.Nickname(r.Context())
+	if who == "" {
+		auth.Unauthorized(w, "Authentication required")
+		return false
+	}
+	if !auth.HasScope(r.Context(), auth.ScopeAdmin) {
+		auth.MissingScope(w, auth.ScopeAdmin)
+		return false
+	}
+	if !strings.EqualFold(who, forum.User) {
+		forbidden(w, "User "+who+" doesn't own forum: "+forum.Slug)
+		return false
+	}
+	return true
+}
+
+// moderate lets moderators and owners remove and restore content in the
+// forum when authentication is enabled.
+func (h *Handlers) moderate(w http.ResponseWriter, r *http.Request, forum string) bool {
+	if !h.auth.Enabled() {
+		return true
+	}
+	who := auth.Nickname(r.Context())
+	if who == "" {
+		auth.Unauthorized(w, "Authentication required")
+		return false
+	}
+	if !auth.HasScope(r.Context(), auth.ScopeModerate) {
+		auth.MissingScope(w, auth.ScopeModerate)
+		return false
+	}
+	return h.allowed(w, r, forum, who, acl.EditOthers)
+}
+
+// allowed responds with 403 when nickname may not perform the action in the
+// forum. A missing forum is left to the store call that follows.
+func (h *Handlers) allowed(w http.ResponseWriter, r *http.Request, forum, nickname string, action acl.Action) bool {
+	ok, err := h.acl.Can(r.Context(), forum, nickname, action)
+	if store.IsNotFound(err, store.Forum) {
+		return true
+	}
+	if err != nil {
+		httputils.Fail(w, r, err)
+		return false
+	}
+	if !ok {
+		mes := models.Message{}
+		mes.Message = "User " + nickname + " can't " + string(action) + " in forum: " + forum
+		httputils.Respond(w, http.StatusForbidden, mes)
+		return false
+	}
+	return true
+}
+
+// mayEdit lets authors edit their own posts and threads, and moderators and
+// owners edit anyone's.
+func (h *Handlers) mayEdit(w http.ResponseWriter, r *http.Request, forum, author string) bool {
+	who := auth.Nickname(r.Context())
+	if who == "" {
+		auth.Unauthorized(w, "Authentication required")
+		return false
+	}
+
+	action, scope := acl.EditOthers, auth.ScopeModerate
+	if strings.EqualFold(who, author) {
+		action, scope = acl.Post, auth.ScopePost
+	}
+	if !auth.HasScope(r.Context(), scope) {
+		auth.MissingScope(w, scope)
+		return false
+	}
+	return h.allowed(w, r, forum, who, action)
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"server/auth"
+	"server/config"
+	"server/database"
+	"server/httputils"
+	"server/models"
+	"server/store"
+	"sync"
+	"time"
+)
+
+const AdminTokenHeader = "X-Admin-Token"
+
+const auditTimeout = 5 * time.Second
+
+type confirmation struct {
+	scope   string
+	expires time.Time
+}
+
+// Service guards the destructive service endpoints. Clearing always needs
+// the admin token and a confirmation token issued by a first, dry request,
+// so it stays disabled until an admin token is configured.
+type Service struct {
+	store      store.Store
+	statements *database.Statements
+	conf       config.Service
+	production bool
+
+	mu            sync.Mutex
+	confirmations map[string]confirmation
+}
+
+// NewService guards the service endpoints of s. statements is listed by the
+// Statements endpoint and may be nil for stores that do not prepare SQL.
+func NewService(s store.Store, statements *database.Statements, conf *config.Config) *Service {
+	return &Service{
+		store:         s,
+		statements:    statements,
+		conf:          conf.Service,
+		production:    conf.Production(),
+		confirmations: map[string]confirmation{},
+	}
+}
+
+func (s *Service) admin(r *http.Request) bool {
+	return adminRequest(r, s.conf.AdminToken)
+}
+
+// adminRequest reports whether the request carries the configured admin
+// token; nothing does while none is configured.
+func adminRequest(r *http.Request, adminToken string) bool {
+	token := r.Header.Get(AdminTokenHeader)
+	return adminToken != "" && token != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
+
+func forbidden(w http.ResponseWriter, message string) {
+	mes := models.Message{}
+	mes.Message = message
+	httputils.Respond(w, http.StatusForbidden, mes)
+}
+
+// Clear empties the database, or a single forum with ?forum=slug.
+func (s *Service) Clear(w http.ResponseWriter, r *http.Request) {
+	forum := r.URL.Query().Get("forum")
+	scope := "all"
+	if forum != "" {
+		scope = "forum " + forum
+	}
+
+	if s.production && !s.conf.AllowClear {
+		s.audit(r, scope, "denied: disabled in production")
+		forbidden(w, "Clearing is disabled in production mode")
+		return
+	}
+
+	if !s.admin(r) {
+		s.audit(r, scope, "denied: no admin credential")
+		forbidden(w, "Clearing requires the "+AdminTokenHeader+" admin credential")
+		return
+	}
+
+	confirm := r.URL.Query().Get("confirm")
+	if confirm == "" {
+		s.requestConfirmation(w, r, scope)
+		return
+	}
+	if !s.confirm(confirm, scope) {
+		s.audit(r, scope, "denied: invalid confirmation")
+		mes := models.Message{}
+		mes.Message = "Invalid or expired confirmation token for " + scope
+		httputils.Respond(w, http.StatusConflict, mes)
+		return
+	}
+
+	var err error
+	if forum == "" {
+		err = s.store.Clear(r.Context())
+	} else {
+		err = s.store.ClearForum(r.Context(), forum)
+	}
+	if store.IsNotFound(err, store.Forum) {
+		s.audit(r, scope, "failed: forum not found")
+		notFound(w, "Can't find forum by slug: "+forum)
+		return
+	}
+	if err != nil {
+		s.audit(r, scope, "failed: "+err.Error())
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	s.audit(r, scope, "ok")
+	httputils.Respond(w, http.StatusOK, nil)
+}
+
+func (s *Service) requestConfirmation(w http.ResponseWriter, r *http.Request, scope string) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+	token := hex.EncodeToString(b)
+
+	expires := time.Now().Add(s.conf.ConfirmTTL)
+
+	s.mu.Lock()
+	for t, c := range s.confirmations {
+		if time.Now().After(c.expires) {
+			delete(s.confirmations, t)
+		}
+	}
+	s.confirmations[token] = confirmation{scope: scope, expires: expires}
+	s.mu.Unlock()
+
+	s.audit(r, scope, "confirmation requested")
+	httputils.Respond(w, http.StatusAccepted, struct {
+		Message string    `json:"message"`
+		Scope   string    `json:"scope"`
+		Confirm string    `json:"confirm"`
+		Expires time.Time `json:"expires"`
+	}{
+		Message: "Repeat the request with ?confirm=<token> to clear " + scope,
+		Scope:   scope,
+		Confirm: token,
+		Expires: expires,
+	})
+}
+
+// confirm consumes a confirmation token issued for the same scope.
+func (s *Service) confirm(token, scope string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.confirmations[token]
+	if !ok {
+		return false
+	}
+	delete(s.confirmations, token)
+	return c.scope == scope && time.Now().Before(c.expires)
+}
+
+// audit records a clear attempt. Only attempts made with the admin
+// credential reach the audit log; anonymous callers could otherwise fill it
+// at will, so their denials go to the process log alone.
+func (s *Service) audit(r *http.Request, scope, result string) {
+	admin := s.admin(r)
+	actor := auth.Nickname(r.Context())
+	if actor == "" && admin {
+		actor = "admin"
+	}
+	if actor == "" {
+		actor = "anonymous"
+	}
+
+	entry := models.AuditEntry{
+		Created:   time.Now(),
+		Action:    "clear",
+		Scope:     scope,
+		Actor:     actor,
+		RequestID: httputils.RequestID(r.Context()),
+		Remote:    r.RemoteAddr,
+		Result:    result,
+	}
+	if !admin {
+		logAudit(entry)
+		return
+	}
+	writeAudit(s.store, entry)
+}
+
+func logAudit(entry models.AuditEntry) {
+	log.Printf("audit: %s %s by %s from %s: %s", entry.Action, entry.Scope, entry.Actor, entry.Remote, entry.Result)
+}
+
+// writeAudit logs the entry and stores it in the audit log.
+func writeAudit(s store.AuditStore, entry models.AuditEntry) {
+	logAudit(entry)
+
+	// The request may already be cancelled; the trail must still be written.
+	ctx, cancel := context.WithTimeout(context.Background(), auditTimeout)
+	defer cancel()
+	if err := s.Audit(ctx, entry); err != nil {
+		log.Println("audit:", err)
+	}
+}
+
+func (s *Service) AuditLog(w http.ResponseWriter, r *http.Request) {
+	if !s.admin(r) {
+		forbidden(w, "The audit log requires the "+AdminTokenHeader+" admin credential")
+		return
+	}
+
+	limit, ok := queryLimit(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := s.store.AuditLog(r.Context(), limit)
+	if err != nil {
+		httputils.Fail(w, r, err)
+		return
+	}
+
+	httputils.Respond(w, http.StatusOK, entries)
+}
+
+// Statements lists the prepared statements with their SQL.
+func (s *Service) Statements(w http.ResponseWriter, r *http.Request) {
+	if !s.admin(r) {
+		forbidden(w, "The statement list requires the "+AdminTokenHeader+" admin credential")
+		return
+	}
+
+	if s.statements == nil {
+		httputils.Respond(w, http.StatusOK, []database.StatementStatus{})
+		return
+	}
+	httputils.Respond(w, http.StatusOK, s.statements.List())
+}
@@ -0,0 +1,132 @@
+package httputils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"server/models"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+type ctxKey int
+
+const entryKey ctxKey = iota
+
+// accessEntry is one JSON access log line. Handlers attach the failure
+// that caused a 5xx through Fail.
+type accessEntry struct {
+	Time       string  `json:"time"`
+	Level      string  `json:"level"`
+	RequestID  string  `json:"request_id"`
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Query      string  `json:"query,omitempty"`
+	Status     int     `json:"status"`
+	Bytes      int     `json:"bytes"`
+	DurationMs float64 `json:"duration_ms"`
+	Remote     string  `json:"remote"`
+	UserAgent  string  `json:"user_agent,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	SQLState   string  `json:"sqlstate,omitempty"`
+	Statement  string  `json:"statement,omitempty"`
+
+	err error
+}
+
+var logOutput = struct {
+	sync.Mutex
+	enc *json.Encoder
+}{enc: json.NewEncoder(os.Stdout)}
+
+// RequestLogger assigns or propagates X-Request-ID and writes one JSON line per request.
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		entry := &accessEntry{
+			RequestID: id,
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Query:     r.URL.RawQuery,
+			Remote:    r.RemoteAddr,
+			UserAgent: r.UserAgent(),
+		}
+		sw := NewStatusWriter(w)
+		start := time.Now()
+
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), entryKey, entry)))
+
+		entry.Time = start.UTC().Format(time.RFC3339Nano)
+		entry.Status = sw.Code()
+		entry.Bytes = sw.Bytes
+		entry.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+		entry.Level = "info"
+		if entry.Status >= http.StatusInternalServerError {
+			entry.Level = "error"
+			if entry.err != nil {
+				entry.Error = entry.err.Error()
+				var pgErr pgx.PgError
+				if errors.As(entry.err, &pgErr) {
+					entry.SQLState = pgErr.Code
+				}
+			}
+		}
+
+		logOutput.Lock()
+		_ = logOutput.enc.Encode(entry)
+		logOutput.Unlock()
+	})
+}
+
+// RequestID returns the id assigned by RequestLogger.
+func RequestID(ctx context.Context) string {
+	if entry, ok := ctx.Value(entryKey).(*accessEntry); ok {
+		return entry.RequestID
+	}
+	return ""
+}
+
+// Fail records the error and the statement that produced it for the access
+// log and responds with 500, or with 503/504 when the pool or the request
+// deadline ran out.
+func Fail(w http.ResponseWriter, r *http.Request, err error) {
+	if entry, ok := r.Context().Value(entryKey).(*accessEntry); ok {
+		entry.err = err
+		var named interface{ StatementName() string }
+		if errors.As(err, &named) {
+			entry.Statement = named.StatementName()
+		}
+	}
+
+	switch {
+	case errors.Is(err, pgx.ErrAcquireTimeout):
+		Respond(w, http.StatusServiceUnavailable, models.Message{Message: "No free database connection, try again later"})
+	case errors.Is(err, context.DeadlineExceeded):
+		Respond(w, http.StatusGatewayTimeout, models.Message{Message: "Request timed out"})
+	case errors.Is(err, context.Canceled):
+		Respond(w, http.StatusServiceUnavailable, models.Message{Message: "Request was cancelled"})
+	default:
+		Respond(w, http.StatusInternalServerError, nil)
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
@@ -1,60 +1,199 @@
 package main
 
 import (
+	"context"
 	"github.com/gorilla/mux"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"server/auth"
+	"server/config"
 	"server/database"
 	handlers "server/handlers"
+	"server/httputils"
+	"server/metrics"
+	"server/store"
+	"server/store/memory"
+	pgstore "server/store/postgres"
+	"syscall"
+	"time"
 )
 
 func main() {
-	postgres, err := database.NewPostgres()
-
+	conf, args, err := config.Load(os.Args[1:])
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	if len(args) != 0 {
+		if args[0] != "migrate" {
+			log.Fatalf("unknown command %q", args[0])
+		}
+		postgres, err := database.NewPostgres(conf.Database)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = migrate(postgres, args[1:])
+		postgres.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	router := mux.NewRouter()
+	router.Use(httputils.Timeout(conf.Server.RequestTimeout, conf.Server.RouteTimeouts))
+
+	var (
+		postgres   *database.Postgres
+		storage    store.Store
+		statements *database.Statements
+	)
+
+	if conf.Mode != "development" && !conf.Production() {
+		log.Fatalf("unknown mode %q, use development or production", conf.Mode)
+	}
+
+	switch conf.Storage {
+	case "postgres":
+		postgres, err = database.NewPostgres(conf.Database)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if conf.Database.MigrateOnStart {
+			done, err := postgres.MigrateUp()
+			if err != nil {
+				log.Fatal(err)
+			}
+			for _, m := range done {
+				log.Printf("Applied migration %04d_%s", m.Version, m.Name)
+			}
+		}
+
+		metrics.RegisterPool(postgres.GetPostgres())
+
+		pg := pgstore.New(postgres)
+		if err := pg.Prepare(); err != nil {
+			log.Fatal(err)
+		}
+		storage, statements = pg, postgres.Statements()
+	case "memory":
+		log.Println("Using the in-memory store, data is lost on exit")
+		storage = memory.New()
+	default:
+		log.Fatalf("unknown storage %q, use postgres or memory", conf.Storage)
+	}
+
+	authenticator := auth.New(storage, conf.Auth)
+	router.Use(authenticator.Middleware)
 
-	handler := handlers.NewHandler(postgres.GetPostgres())
+	handler := handlers.NewHandler(storage, authenticator, conf.Service.AdminToken)
+	health := handlers.NewHealth(postgres)
+	admin := handlers.NewService(storage, statements, conf)
 
-	handler.Prepare()
+	router.HandleFunc("/healthz", health.Live).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", health.Ready).Methods(http.MethodGet)
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 
 	user := router.PathPrefix("/api/user").Subrouter()
+	user.HandleFunc("/login", handler.Login).Methods(http.MethodPost)
+	user.HandleFunc("/logout", handler.Logout).Methods(http.MethodPost)
+	user.HandleFunc("/search", handler.SearchUsers).Methods(http.MethodGet)
 	user.HandleFunc("/{nickname}/create", handler.CreateUser).Methods(http.MethodPost)
 	user.HandleFunc("/{nickname}/profile", handler.GetUser).Methods(http.MethodGet)
 	user.HandleFunc("/{nickname}/profile", handler.ChangeUser).Methods(http.MethodPost)
+	user.HandleFunc("/{nickname}/threads", handler.GetUserThreads).Methods(http.MethodGet)
+	user.HandleFunc("/{nickname}/posts", handler.GetUserPosts).Methods(http.MethodGet)
+	user.HandleFunc("/{nickname}/export", handler.ExportUser).Methods(http.MethodGet)
+	user.HandleFunc("/{nickname}", handler.DeleteUser).Methods(http.MethodDelete)
+	user.HandleFunc("/{nickname}/keys", handler.CreateAPIKey).Methods(http.MethodPost)
+	user.HandleFunc("/{nickname}/keys", handler.GetAPIKeys).Methods(http.MethodGet)
+	user.HandleFunc("/{nickname}/keys/{id}", handler.RevokeAPIKey).Methods(http.MethodDelete)
 
 	forum := router.PathPrefix("/api/forum").Subrouter()
 	forum.HandleFunc("/create", handler.CreateForum).Methods(http.MethodPost)
+	forum.HandleFunc("/list", handler.GetForums).Methods(http.MethodGet)
+	forum.HandleFunc("/tree", handler.ForumTree).Methods(http.MethodGet)
 	forum.HandleFunc("/{slug}/details", handler.GetForum).Methods(http.MethodGet)
+	forum.HandleFunc("/{slug}/details", handler.UpdateForum).Methods(http.MethodPost)
+	forum.HandleFunc("/{slug}", handler.DeleteForum).Methods(http.MethodDelete)
+	forum.HandleFunc("/{slug}/move", handler.MoveForum).Methods(http.MethodPost)
 	forum.HandleFunc("/{slug}/create", handler.CreateThread).Methods(http.MethodPost)
 	forum.HandleFunc("/{slug}/users", handler.GetForumUsers).Methods(http.MethodGet)
 	forum.HandleFunc("/{slug}/threads", handler.GetForumThreads).Methods(http.MethodGet)
+	forum.HandleFunc("/{slug}/roles", handler.GetForumRoles).Methods(http.MethodGet)
+	forum.HandleFunc("/{slug}/roles", handler.SetForumRole).Methods(http.MethodPost)
+	forum.HandleFunc("/{slug}/roles/{nickname}", handler.DeleteForumRole).Methods(http.MethodDelete)
+
+	category := router.PathPrefix("/api/category").Subrouter()
+	category.HandleFunc("/create", handler.CreateCategory).Methods(http.MethodPost)
 
 	post := router.PathPrefix("/api/post").Subrouter()
 	post.HandleFunc("/{id}/details", handler.GetPost).Methods(http.MethodGet)
 	post.HandleFunc("/{id}/details", handler.ChangePost).Methods(http.MethodPost)
+	post.HandleFunc("/{id}/history", handler.PostHistory).Methods(http.MethodGet)
+	post.HandleFunc("/{id}/rollback", handler.RollbackPost).Methods(http.MethodPost)
+	post.HandleFunc("/{id}", handler.DeletePost).Methods(http.MethodDelete)
 
 	thread := router.PathPrefix("/api/thread").Subrouter()
 	thread.HandleFunc("/{slug_or_id}/create", handler.CreatePost).Methods(http.MethodPost)
 	thread.HandleFunc("/{slug_or_id}/details", handler.GetThread).Methods(http.MethodGet)
 	thread.HandleFunc("/{slug_or_id}/details", handler.ChangeThread).Methods(http.MethodPost)
 	thread.HandleFunc("/{slug_or_id}/vote", handler.CreateVote).Methods(http.MethodPost)
+	thread.HandleFunc("/{slug_or_id}/restore", handler.RestoreThread).Methods(http.MethodPost)
+	thread.HandleFunc("/{slug_or_id}", handler.DeleteThread).Methods(http.MethodDelete)
 	thread.HandleFunc("/{slug_or_id}/posts", handler.ThreadPosts).Methods(http.MethodGet)
 
 	service := router.PathPrefix("/api/service").Subrouter()
-	service.HandleFunc("/clear", handler.AllClear).Methods(http.MethodPost)
+	service.HandleFunc("/clear", admin.Clear).Methods(http.MethodPost)
+	service.HandleFunc("/audit", admin.AuditLog).Methods(http.MethodGet)
 	service.HandleFunc("/status", handler.AllInfo).Methods(http.MethodGet)
+	service.HandleFunc("/statements", admin.Statements).Methods(http.MethodGet)
 
 	server := &http.Server{
-		Handler: router,
-		Addr:    ":5000",
+		Handler:      httputils.RequestLogger(metrics.Middleware(router)),
+		Addr:         conf.Server.Addr,
+		ReadTimeout:  conf.Server.ReadTimeout,
+		WriteTimeout: conf.Server.WriteTimeout,
+		IdleTimeout:  conf.Server.IdleTimeout,
 	}
 
-	log.Println("Server starting")
-	if err := server.ListenAndServe(); err != nil {
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Println("Server starting on", conf.Server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+
+	select {
+	case err := <-serverErr:
+		if postgres != nil {
+			postgres.Close()
+		}
 		log.Fatal(err)
+	case sig := <-stop:
+		log.Printf("Received %s, draining requests for up to %s", sig, conf.Server.ShutdownTimeout)
+	}
+	signal.Stop(stop)
+	health.Drain()
+	time.Sleep(conf.Server.DrainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), conf.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println("Shutdown deadline exceeded, closing remaining connections:", err)
+		_ = server.Close()
+	}
+
+	if postgres != nil {
+		if err := postgres.Close(); err != nil {
+			log.Println(err)
+		}
 	}
+	log.Println("Server stopped")
 }
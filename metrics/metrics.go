@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"net/http"
+	"server/httputils"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "forum",
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "forum",
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "forum",
+		Name:      "db_query_duration_seconds",
+		Help:      "Database query latency by prepared statement name.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"statement"})
+
+	queryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "forum",
+		Name:      "db_query_errors_total",
+		Help:      "Failed database queries by prepared statement name.",
+	}, []string{"statement"})
+
+	poolWaits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "forum",
+		Name:      "db_pool_waits_total",
+		Help:      "Times a request had to wait for a free pool connection.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(requests, requestDuration, queryDuration, queryErrors, poolWaits)
+}
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware records request counts and latency labelled with the mux route
+// template. It wraps the whole router rather than being installed with
+// router.Use, so requests that match no route (404) or no method (405) are
+// counted too, under the "unmatched" route label.
+func Middleware(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unmatched"
+		var match mux.RouteMatch
+		if router.Match(r, &match) && match.Route != nil {
+			if tpl, err := match.Route.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+
+		sw := httputils.NewStatusWriter(w)
+		start := time.Now()
+		router.ServeHTTP(sw, r)
+
+		requestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		requests.WithLabelValues(route, r.Method, strconv.Itoa(sw.Code())).Inc()
+	})
+}
+
+func ObserveQuery(statement string, d time.Duration, err error) {
+	if err != nil {
+		queryErrors.WithLabelValues(statement).Inc()
+		return
+	}
+	queryDuration.WithLabelValues(statement).Observe(d.Seconds())
+}
+
+func ObservePoolWait() {
+	poolWaits.Inc()
+}
+
+// RegisterPool exports the connection pool gauges.
+func RegisterPool(pool *pgx.ConnPool) {
+	gauge := func(name, help string, value func(s pgx.ConnPoolStat) int) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "forum",
+			Name:      name,
+			Help:      help,
+		}, func() float64 {
+			return float64(value(pool.Stat()))
+		})
+	}
+
+	prometheus.MustRegister(
+		gauge("db_pool_max_connections", "Configured pool size.",
+			func(s pgx.ConnPoolStat) int { return s.MaxConnections }),
+		gauge("db_pool_connections", "Open pool connections.",
+			func(s pgx.ConnPoolStat) int { return s.CurrentConnections }),
+		gauge("db_pool_available_connections", "Idle pool connections.",
+			func(s pgx.ConnPoolStat) int { return s.AvailableConnections }),
+		gauge("db_pool_acquired_connections", "Pool connections checked out by requests.",
+			func(s pgx.ConnPoolStat) int { return s.CheckedOutConnections() }),
+	)
+}
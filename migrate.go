@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"server/database"
+	"strconv"
+)
+
+const migrateUsage = "usage: main [flags] migrate up | down [steps] | status | version"
+
+func migrate(postgres *database.Postgres, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := postgres.MigrateUp()
+		for _, m := range done {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("migrate down: steps must be a positive number")
+			}
+			steps = n
+		}
+		done, err := postgres.MigrateDown(steps)
+		for _, m := range done {
+			fmt.Printf("rolled back %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		migrations, err := postgres.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			applied := "pending"
+			if m.AppliedAt != nil {
+				applied = m.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", m.Version, m.Name, applied)
+		}
+		return nil
+	case "version":
+		version, err := postgres.Version()
+		if err != nil {
+			return err
+		}
+		fmt.Println(version)
+		return nil
+	}
+
+	return fmt.Errorf(migrateUsage)
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type AuditEntry struct {
+	Id        int       `json:"id"`
+	Created   time.Time `json:"created"`
+	Action    string    `json:"action"`
+	Scope     string    `json:"scope"`
+	Actor     string    `json:"actor"`
+	RequestID string    `json:"requestId"`
+	Remote    string    `json:"remote"`
+	Result    string    `json:"result"`
+}
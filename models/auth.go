@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type Credentials struct {
+	Nickname string `json:"nickname"`
+	Password string `json:"password"`
+}
+
+// Token is a login session. Stores keep only the SHA-256 of the bearer
+// token in Token; the plain value is returned once by the login endpoint.
+type Token struct {
+	Token    string    `json:"token"`
+	Nickname string    `json:"nickname"`
+	Created  time.Time `json:"created"`
+	Expires  time.Time `json:"expires"`
+}
+
+// APIKey gives a bot limited access on behalf of a user. Key holds the
+// plain key only in the response that created it.
+type APIKey struct {
+	Id       int        `json:"id"`
+	Nickname string     `json:"nickname"`
+	Name     string     `json:"name"`
+	Scopes   []string   `json:"scopes"`
+	Key      string     `json:"key,omitempty"`
+	Hash     string     `json:"-"`
+	Created  time.Time  `json:"created"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	LastUsed *time.Time `json:"lastUsed,omitempty"`
+	Revoked  *time.Time `json:"revoked,omitempty"`
+}
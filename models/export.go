@@ -0,0 +1,13 @@
+package models
+
+// ExportVote and Membership are the votes and forum_users rows of a user in
+// a personal data export.
+type ExportVote struct {
+	Thread int `json:"thread"`
+	Voice  int `json:"voice"`
+}
+
+type Membership struct {
+	Forum string `json:"forum"`
+	Role  string `json:"role,omitempty"`
+}
@@ -1,9 +1,49 @@
 package models
 
 type Forum struct {
-	Title   string `json:"title" db:"title"`
-	User    string `json:"user" db:"user"`
-	Slug    string `json:"slug" db:"slug"`
-	Posts   int    `json:"posts" db:"posts"`
-	Threads int    `json:"threads" db:"threads"`
+	Title    string `json:"title" db:"title"`
+	User     string `json:"user" db:"user"`
+	Slug     string `json:"slug" db:"slug"`
+	Posts    int    `json:"posts" db:"posts"`
+	Threads  int    `json:"threads" db:"threads"`
+	Parent   string `json:"parent,omitempty" db:"parent"`
+	Category string `json:"category,omitempty" db:"category"`
+	Position int    `json:"position,omitempty" db:"position"`
+}
+
+// ForumMove is where a forum goes: under Parent, or at the top of Category
+// when Parent is empty, at Position among its new siblings.
+type ForumMove struct {
+	Parent   string `json:"parent"`
+	Category string `json:"category"`
+	Position int    `json:"position"`
+}
+
+type Category struct {
+	Slug     string `json:"slug"`
+	Title    string `json:"title"`
+	Position int    `json:"position"`
+}
+
+// ForumNode is a forum with its sub-forums; the totals add up the counters
+// of the whole subtree.
+type ForumNode struct {
+	Forum
+	TotalPosts   int         `json:"totalPosts"`
+	TotalThreads int         `json:"totalThreads"`
+	Children     []ForumNode `json:"children"`
+}
+
+type CategoryNode struct {
+	Category
+	TotalPosts   int         `json:"totalPosts"`
+	TotalThreads int         `json:"totalThreads"`
+	Forums       []ForumNode `json:"forums"`
+}
+
+// ForumTree lists the categories and, under Forums, the top-level forums
+// without one.
+type ForumTree struct {
+	Categories []CategoryNode `json:"categories"`
+	Forums     []ForumNode    `json:"forums"`
 }
@@ -11,4 +11,9 @@ type Post struct {
 	Forum    string    `json:"forum" db:"forum"`
 	Thread   int       `json:"thread" db:"thread"`
 	Created  time.Time `json:"created" db:"created"`
+	// IsDeleted marks a tombstone: the message is DeletedMessage and the
+	// author is hidden.
+	IsDeleted bool `json:"isDeleted,omitempty" db:"deleted"`
 }
+
+const DeletedMessage = "[deleted]"
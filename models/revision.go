@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// PostRevision is one edit of a post: Message holds the text the edit
+// replaced and Diff, filled in by the history endpoint, the line changes.
+type PostRevision struct {
+	Id      int       `json:"id"`
+	Post    int       `json:"post"`
+	Editor  string    `json:"editor,omitempty"`
+	Message string    `json:"message"`
+	Created time.Time `json:"created"`
+	Diff    []string  `json:"diff,omitempty"`
+}
+
+type PostHistory struct {
+	Post      Post           `json:"post"`
+	Revisions []PostRevision `json:"revisions"`
+}
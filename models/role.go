@@ -0,0 +1,7 @@
+package models
+
+type ForumRole struct {
+	Forum    string `json:"forum"`
+	Nickname string `json:"nickname"`
+	Role     string `json:"role"`
+}
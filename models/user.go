@@ -6,3 +6,13 @@ type User struct {
 	About    string `json:"about" db:"about"`
 	Email    string `json:"email" db:"email"`
 }
+
+// UserDeletion reports what AnonymizeUser handed over to the ghost account
+// and what it removed.
+type UserDeletion struct {
+	Ghost   string `json:"ghost"`
+	Forums  int    `json:"forums"`
+	Threads int    `json:"threads"`
+	Posts   int    `json:"posts"`
+	Votes   int    `json:"votes"`
+}
@@ -0,0 +1,334 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"server/models"
+	"server/store"
+	"sort"
+	"testing"
+	"time"
+)
+
+// The tests in this file hold both stores to the contract of store.Store.
+
+var epoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func createUsers(t *testing.T, s store.Store, nicknames ...string) {
+	t.Helper()
+	for _, nickname := range nicknames {
+		user := models.User{Nickname: nickname, Fullname: nickname, Email: nickname + "@example.com"}
+		if _, err := s.CreateUser(context.Background(), user, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func createForum(t *testing.T, s store.Store, slug, owner string) {
+	t.Helper()
+	if _, err := s.CreateForum(context.Background(), models.Forum{Slug: slug, Title: slug, User: owner}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// createThread creates a thread created minutes after the epoch.
+func createThread(t *testing.T, s store.Store, forum, author string, minutes int) models.Thread {
+	t.Helper()
+	thread := models.Thread{Forum: forum, Author: author, Title: "title", Message: "message", Created: epoch.Add(time.Duration(minutes) * time.Minute)}
+	thread, err := s.CreateThread(context.Background(), thread)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return thread
+}
+
+func createPosts(t *testing.T, s store.Store, thread int, author string, n int) []models.Post {
+	t.Helper()
+	posts := make([]models.Post, n)
+	for i := range posts {
+		posts[i] = models.Post{Author: author, Message: "message"}
+	}
+	posts, err := s.CreatePosts(context.Background(), store.ThreadRef{ID: thread}, posts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return posts
+}
+
+func threadIDs(threads []models.Thread) []int {
+	ids := []int{}
+	for _, t := range threads {
+		ids = append(ids, t.Id)
+	}
+	return ids
+}
+
+func postIDs(posts []models.Post) []int {
+	ids := []int{}
+	for _, p := range posts {
+		ids = append(ids, p.Id)
+	}
+	return ids
+}
+
+func TestUserConflicts(t *testing.T) {
+	eachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		createUsers(t, s, "alice", "bob")
+
+		tests := []struct {
+			name string
+			user models.User
+			want []string
+		}{
+			{"nickname", models.User{Nickname: "ALICE", Email: "new@example.com"}, []string{"alice"}},
+			{"email", models.User{Nickname: "carol", Email: "Bob@Example.com"}, []string{"bob"}},
+			{"both", models.User{Nickname: "alice", Email: "bob@example.com"}, []string{"alice", "bob"}},
+			{"own email", models.User{Nickname: "alice", Email: "alice@example.com"}, []string{"alice"}},
+		}
+		for _, tt := range tests {
+			users, err := s.CreateUser(ctx, tt.user, "")
+			if !errors.Is(err, store.ErrConflict) {
+				t.Errorf("%s: got %v, want ErrConflict", tt.name, err)
+				continue
+			}
+			names := nicknames(users)
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("%s: got %q, want %q", tt.name, names, tt.want)
+			}
+		}
+
+		if _, err := s.UpdateUser(ctx, models.User{Nickname: "alice", Email: "BOB@example.com"}); !errors.Is(err, store.ErrConflict) {
+			t.Errorf("update to a taken email: got %v, want ErrConflict", err)
+		}
+		if _, err := s.UpdateUser(ctx, models.User{Nickname: "nobody", About: "x"}); !store.IsNotFound(err, store.User) {
+			t.Errorf("update of a missing user: got %v, want user not found", err)
+		}
+		if _, err := s.GetUser(ctx, "nobody"); !store.IsNotFound(err, store.User) {
+			t.Errorf("missing user: got %v, want user not found", err)
+		}
+	})
+}
+
+func TestForumConflicts(t *testing.T) {
+	eachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		createUsers(t, s, "alice")
+		createForum(t, s, "pirates", "alice")
+
+		existing, err := s.CreateForum(ctx, models.Forum{Slug: "PIRATES", Title: "Other", User: "alice"})
+		if !errors.Is(err, store.ErrConflict) || existing.Slug != "pirates" || existing.Title != "pirates" {
+			t.Errorf("taken slug: got %+v, %v, want the existing forum and ErrConflict", existing, err)
+		}
+		if _, err := s.CreateForum(ctx, models.Forum{Slug: "ghosts", Title: "Ghosts", User: "nobody"}); !store.IsNotFound(err, store.User) {
+			t.Errorf("missing owner: got %v, want user not found", err)
+		}
+
+		// A soft-deleted forum keeps its slug without showing itself.
+		if err := s.DeleteForum(ctx, "pirates", true); err != nil {
+			t.Fatal(err)
+		}
+		hidden, err := s.CreateForum(ctx, models.Forum{Slug: "pirates", Title: "Again", User: "alice"})
+		if !errors.Is(err, store.ErrConflict) || hidden.Slug != "" {
+			t.Errorf("slug of a deleted forum: got %+v, %v, want an empty forum and ErrConflict", hidden, err)
+		}
+	})
+}
+
+func TestKeysetPaging(t *testing.T) {
+	eachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		createUsers(t, s, "alice", "Bob", "carol", "dave")
+		createForum(t, s, "pirates", "alice")
+		var threads []models.Thread
+		for i, nickname := range []string{"dave", "carol", "Bob", "alice"} {
+			threads = append(threads, createThread(t, s, "pirates", nickname, i))
+		}
+		// Two more threads at the same time, told apart by their ids.
+		threads = append(threads, createThread(t, s, "pirates", "alice", 3), createThread(t, s, "pirates", "alice", 3))
+		posts := createPosts(t, s, threads[0].Id, "alice", 5)
+
+		users, err := s.ForumUsers(ctx, "pirates", store.UsersQuery{Limit: 2, Since: "bob"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := nicknames(users), []string{"carol", "dave"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("forum users: got %q, want %q", got, want)
+		}
+		users, err = s.ForumUsers(ctx, "pirates", store.UsersQuery{Limit: 2, Since: "CAROL", Desc: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := nicknames(users), []string{"Bob", "alice"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("forum users, desc: got %q, want %q", got, want)
+		}
+
+		// Since is inclusive for forum threads.
+		since := threads[1].Created
+		forumThreads, err := s.ForumThreads(ctx, "pirates", store.ThreadsQuery{Limit: 2, Since: &since})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := threadIDs(forumThreads), []int{threads[1].Id, threads[2].Id}; !reflect.DeepEqual(got, want) {
+			t.Errorf("forum threads: got %v, want %v", got, want)
+		}
+		forumThreads, err = s.ForumThreads(ctx, "pirates", store.ThreadsQuery{Limit: 10, Since: &since, Desc: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := threadIDs(forumThreads), []int{threads[1].Id, threads[0].Id}; !reflect.DeepEqual(got, want) {
+			t.Errorf("forum threads, desc: got %v, want %v", got, want)
+		}
+
+		// With SinceID the user threads continue after (created, id).
+		last := threads[4]
+		tests := []struct {
+			name  string
+			query store.ActivityQuery
+			want  []int
+		}{
+			{"first page", store.ActivityQuery{Limit: 2, Desc: true}, []int{threads[5].Id, threads[4].Id}},
+			{"next page", store.ActivityQuery{Limit: 2, Desc: true, Since: &last.Created, SinceID: last.Id}, []int{threads[3].Id}},
+			{"ascending", store.ActivityQuery{Limit: 10, Since: &last.Created, SinceID: last.Id}, []int{threads[5].Id}},
+			{"inclusive since", store.ActivityQuery{Limit: 10, Since: &last.Created}, []int{threads[3].Id, threads[4].Id, threads[5].Id}},
+		}
+		for _, tt := range tests {
+			got, err := s.UserThreads(ctx, "alice", tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := threadIDs(got); !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("user threads, %s: got %v, want %v", tt.name, ids, tt.want)
+			}
+		}
+
+		flat, err := s.ThreadPosts(ctx, store.ThreadRef{ID: threads[0].Id}, store.PostsQuery{Limit: 2, Since: posts[1].Id, Sort: "flat"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := postIDs(flat), []int{posts[2].Id, posts[3].Id}; !reflect.DeepEqual(got, want) {
+			t.Errorf("thread posts: got %v, want %v", got, want)
+		}
+		flat, err = s.ThreadPosts(ctx, store.ThreadRef{ID: threads[0].Id}, store.PostsQuery{Limit: 2, Since: posts[3].Id, Sort: "flat", Desc: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := postIDs(flat), []int{posts[2].Id, posts[1].Id}; !reflect.DeepEqual(got, want) {
+			t.Errorf("thread posts, desc: got %v, want %v", got, want)
+		}
+	})
+}
+
+func TestVoteToggling(t *testing.T) {
+	eachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		createUsers(t, s, "alice", "bob")
+		createForum(t, s, "pirates", "alice")
+		ref := store.ThreadRef{ID: createThread(t, s, "pirates", "alice", 0).Id}
+
+		steps := []struct {
+			vote models.Vote
+			want int
+		}{
+			{models.Vote{Nickname: "alice", Voice: 1}, 1},
+			{models.Vote{Nickname: "ALICE", Voice: 1}, 1},
+			{models.Vote{Nickname: "bob", Voice: 1}, 2},
+			{models.Vote{Nickname: "alice", Voice: -1}, 0},
+			{models.Vote{Nickname: "bob", Voice: -1}, -2},
+			{models.Vote{Nickname: "bob", Voice: 1}, 0},
+		}
+		for i, step := range steps {
+			thread, err := s.Vote(ctx, ref, step.vote)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if thread.Votes != step.want {
+				t.Errorf("step %d, %s votes %d: got %d, want %d", i, step.vote.Nickname, step.vote.Voice, thread.Votes, step.want)
+			}
+		}
+
+		if _, err := s.Vote(ctx, ref, models.Vote{Nickname: "nobody", Voice: 1}); !store.IsNotFound(err, store.User) {
+			t.Errorf("missing voter: got %v, want user not found", err)
+		}
+		if _, err := s.Vote(ctx, store.ThreadRef{ID: ref.ID + 100}, models.Vote{Nickname: "bob", Voice: 1}); !store.IsNotFound(err, store.Thread) {
+			t.Errorf("missing thread: got %v, want thread not found", err)
+		}
+	})
+}
+
+func TestSoftDelete(t *testing.T) {
+	eachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		createUsers(t, s, "alice", "bob")
+		createForum(t, s, "pirates", "alice")
+		kept := createThread(t, s, "pirates", "alice", 0)
+		gone := createThread(t, s, "pirates", "bob", 1)
+		posts := createPosts(t, s, kept.Id, "alice", 2)
+		createPosts(t, s, gone.Id, "bob", 3)
+
+		counters := func(name string, threads, posts int) {
+			t.Helper()
+			f, err := s.GetForum(ctx, "pirates")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Threads != threads || f.Posts != posts {
+				t.Errorf("%s: counters %d/%d, want %d/%d", name, f.Threads, f.Posts, threads, posts)
+			}
+		}
+		counters("before", 2, 5)
+
+		ref := store.ThreadRef{ID: gone.Id}
+		if _, err := s.DeleteThread(ctx, ref, true); err != nil {
+			t.Fatal(err)
+		}
+		counters("thread deleted", 1, 2)
+		if _, err := s.GetThread(ctx, ref); !store.IsNotFound(err, store.Thread) {
+			t.Errorf("deleted thread: got %v, want thread not found", err)
+		}
+		if _, err := s.DeleteThread(ctx, ref, true); !store.IsNotFound(err, store.Thread) {
+			t.Errorf("deleting twice: got %v, want thread not found", err)
+		}
+		users, err := s.ForumUsers(ctx, "pirates", store.UsersQuery{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := nicknames(users), []string{"alice"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("forum users: got %q, want %q", got, want)
+		}
+
+		if _, err := s.RestoreThread(ctx, ref); err != nil {
+			t.Fatal(err)
+		}
+		counters("thread restored", 2, 5)
+
+		// A deleted post stays in the tree and in the counters.
+		deleted, err := s.DeletePost(ctx, posts[0].Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !deleted.IsDeleted || deleted.Message != models.DeletedMessage || deleted.Author != "" {
+			t.Errorf("tombstone: got %+v", deleted)
+		}
+		if _, err := s.DeletePost(ctx, posts[0].Id); !store.IsNotFound(err, store.Post) {
+			t.Errorf("deleting a post twice: got %v, want post not found", err)
+		}
+		counters("post deleted", 2, 5)
+
+		// The threads of a soft-deleted forum go with it.
+		if err := s.DeleteForum(ctx, "pirates", true); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.GetForum(ctx, "pirates"); !store.IsNotFound(err, store.Forum) {
+			t.Errorf("deleted forum: got %v, want forum not found", err)
+		}
+		if _, err := s.GetDeletedForum(ctx, "pirates"); err != nil {
+			t.Errorf("GetDeletedForum: %v", err)
+		}
+		if _, err := s.GetThread(ctx, store.ThreadRef{ID: kept.Id}); !store.IsNotFound(err, store.Thread) {
+			t.Errorf("thread of a deleted forum: got %v, want thread not found", err)
+		}
+	})
+}
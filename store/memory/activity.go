@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"server/models"
+	"server/store"
+	"sort"
+	"time"
+)
+
+// activityFilter checks the user and forum of q and reports whether an item
+// of the user with the given forum, creation time and id is listed.
+func (s *Store) activityFilter(nickname string, q store.ActivityQuery) (func(forum string, created time.Time, id int) bool, string, error) {
+	nickname, err := s.canonical(nickname)
+	if err != nil {
+		return nil, "", err
+	}
+	if q.Forum != "" {
+		if _, err := s.forum(q.Forum); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return func(forum string, created time.Time, id int) bool {
+		if q.Forum != "" && key(forum) != key(q.Forum) {
+			return false
+		}
+		if _, err := s.forum(forum); err != nil {
+			return false
+		}
+		return q.Since == nil || activityLess(q.Desc, *q.Since, created, q.SinceBound(), id)
+	}, key(nickname), nil
+}
+
+// activityLess orders by creation time and then id, like the statements.
+func activityLess(desc bool, ci, cj time.Time, idi, idj int) bool {
+	if desc {
+		ci, cj, idi, idj = cj, ci, idj, idi
+	}
+	if !ci.Equal(cj) {
+		return ci.Before(cj)
+	}
+	return idi < idj
+}
+
+func (s *Store) UserThreads(ctx context.Context, nickname string, q store.ActivityQuery) ([]models.Thread, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	listed, k, err := s.activityFilter(nickname, q)
+	if err != nil {
+		return nil, err
+	}
+
+	threads := []models.Thread{}
+	for _, t := range s.threads {
+		if key(t.Author) == k && t.deleted == nil && listed(t.Forum, t.Created, t.Id) {
+			threads = append(threads, t.Thread)
+		}
+	}
+
+	sort.Slice(threads, func(i, j int) bool {
+		return activityLess(q.Desc, threads[i].Created, threads[j].Created, threads[i].Id, threads[j].Id)
+	})
+
+	if q.Limit >= 0 && len(threads) > q.Limit {
+		threads = threads[:q.Limit]
+	}
+	return threads, nil
+}
+
+func (s *Store) UserPosts(ctx context.Context, nickname string, q store.ActivityQuery) ([]models.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	listed, k, err := s.activityFilter(nickname, q)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := []models.Post{}
+	for _, p := range s.posts {
+		if key(p.Author) != k || p.IsDeleted || !listed(p.Forum, p.Created, p.Id) {
+			continue
+		}
+		if t, ok := s.threads[p.Thread]; !ok || t.deleted != nil {
+			continue
+		}
+		posts = append(posts, p.Post)
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		return activityLess(q.Desc, posts[i].Created, posts[j].Created, posts[i].Id, posts[j].Id)
+	})
+
+	if q.Limit >= 0 && len(posts) > q.Limit {
+		posts = posts[:q.Limit]
+	}
+	return posts, nil
+}
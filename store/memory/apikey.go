@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"server/models"
+	"server/store"
+	"sort"
+	"strconv"
+	"time"
+)
+
+func (s *Store) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nickname, err := s.canonical(key.Nickname)
+	if err != nil {
+		return key, err
+	}
+	key.Nickname = nickname
+	key.Key = ""
+
+	s.apiKeySeq++
+	key.Id = s.apiKeySeq
+	k := key
+	s.apiKeys[key.Id] = &k
+	return key, nil
+}
+
+func (s *Store) APIKeys(ctx context.Context, nickname string) ([]models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, err := s.canonical(nickname); err != nil {
+		return nil, err
+	}
+
+	keys := []models.APIKey{}
+	for _, k := range s.apiKeys {
+		if key(k.Nickname) == key(nickname) {
+			keys = append(keys, *k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Id < keys[j].Id
+	})
+	return keys, nil
+}
+
+func (s *Store) GetAPIKey(ctx context.Context, hash string) (models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.apiKeys {
+		if k.Hash == hash {
+			return *k, nil
+		}
+	}
+	return models.APIKey{}, store.NotFound(store.APIKey, hash)
+}
+
+func (s *Store) RevokeAPIKey(ctx context.Context, nickname string, id int) (models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.apiKeys[id]
+	if !ok || key(k.Nickname) != key(nickname) {
+		return models.APIKey{}, store.NotFound(store.APIKey, strconv.Itoa(id))
+	}
+
+	if k.Revoked == nil {
+		now := time.Now()
+		k.Revoked = &now
+	}
+	return *k, nil
+}
+
+func (s *Store) TouchAPIKey(ctx context.Context, id int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if k, ok := s.apiKeys[id]; ok {
+		k.LastUsed = &at
+	}
+	return nil
+}
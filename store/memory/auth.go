@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"server/models"
+	"server/store"
+)
+
+func (s *Store) PasswordHash(ctx context.Context, nickname string) (string, string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hash, ok := s.passwords[key(nickname)]
+	if !ok {
+		return nickname, "", store.NotFound(store.User, nickname)
+	}
+	return s.users[key(nickname)].Nickname, hash, nil
+}
+
+func (s *Store) CreateToken(ctx context.Context, token models.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nickname, err := s.canonical(token.Nickname)
+	if err != nil {
+		return err
+	}
+	token.Nickname = nickname
+	s.tokens[token.Token] = token
+	return nil
+}
+
+func (s *Store) GetToken(ctx context.Context, hash string) (models.Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.tokens[hash]
+	if !ok {
+		return token, store.NotFound(store.Token, hash)
+	}
+	return token, nil
+}
+
+func (s *Store) DeleteToken(ctx context.Context, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tokens, hash)
+	return nil
+}
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+	"server/models"
+	"server/store"
+)
+
+func (s *Store) CreateCategory(ctx context.Context, c models.Category) (models.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.categories[key(c.Slug)]; ok {
+		return existing, store.ErrConflict
+	}
+	s.categories[key(c.Slug)] = c
+	return c, nil
+}
+
+func (s *Store) ForumTree(ctx context.Context) (models.ForumTree, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	categories := make([]models.Category, 0, len(s.categories))
+	for _, c := range s.categories {
+		categories = append(categories, c)
+	}
+	forums := []models.Forum{}
+	for _, f := range s.forums {
+		if f.deleted == nil {
+			forums = append(forums, f.Forum)
+		}
+	}
+	return store.BuildForumTree(categories, forums), nil
+}
+
+func (s *Store) MoveForum(ctx context.Context, slug string, move models.ForumMove) (models.Forum, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.forum(slug)
+	if err != nil {
+		return models.Forum{}, err
+	}
+
+	// A sub-forum always sits in the category of its parent.
+	if move.Parent != "" {
+		parent, err := s.forum(move.Parent)
+		if err != nil {
+			return models.Forum{}, err
+		}
+		for p := parent; p != nil; p = s.forums[key(p.Parent)] {
+			if key(p.Slug) == key(f.Slug) {
+				return models.Forum{}, store.ErrConflict
+			}
+		}
+		move.Parent, move.Category = parent.Slug, parent.Category
+	} else if move.Category != "" {
+		c, ok := s.categories[key(move.Category)]
+		if !ok {
+			return models.Forum{}, store.NotFound(store.Category, move.Category)
+		}
+		move.Category = c.Slug
+	}
+
+	f.Parent, f.Category, f.Position = move.Parent, move.Category, move.Position
+	s.moveSubForums(f.Slug, f.Category)
+	return f.Forum, nil
+}
+
+// moveSubForums puts every descendant of the forum into category; the
+// caller holds the lock.
+func (s *Store) moveSubForums(slug, category string) {
+	for _, child := range s.forums {
+		if key(child.Parent) == key(slug) {
+			child.Category = category
+			s.moveSubForums(child.Slug, category)
+		}
+	}
+}
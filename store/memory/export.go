@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"server/models"
+	"server/store"
+	"sort"
+)
+
+func (s *Store) ExportUser(ctx context.Context, nickname string, emit func(section string, record interface{}) error) error {
+	// The records are copied under the lock and emitted after it, so that a
+	// slow client does not hold up the store.
+	sections, err := s.exportRecords(nickname)
+	if err != nil {
+		return err
+	}
+	for _, section := range store.ExportSections {
+		for _, record := range sections[section] {
+			if err := emit(section, record); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Store) exportRecords(nickname string) (map[string][]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[key(nickname)]
+	if !ok {
+		return nil, store.NotFound(store.User, nickname)
+	}
+	k := key(u.Nickname)
+	sections := map[string][]interface{}{store.ExportProfile: {*u}}
+
+	slugs := make([]string, 0, len(s.forums))
+	for slug := range s.forums {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+	for _, slug := range slugs {
+		f := s.forums[slug]
+		if key(f.User) == k {
+			sections[store.ExportForums] = append(sections[store.ExportForums], f.Forum)
+		}
+		if _, ok := f.users[k]; ok {
+			m := models.Membership{Forum: f.Slug, Role: f.roles[k].Role}
+			sections[store.ExportMemberships] = append(sections[store.ExportMemberships], m)
+		}
+	}
+
+	for id := 1; id <= s.threadSeq; id++ {
+		if t, ok := s.threads[id]; ok && key(t.Author) == k {
+			sections[store.ExportThreads] = append(sections[store.ExportThreads], t.Thread)
+		}
+	}
+	for id := 1; id <= s.postSeq; id++ {
+		if p, ok := s.posts[id]; ok && key(p.Author) == k {
+			sections[store.ExportPosts] = append(sections[store.ExportPosts], p.public())
+		}
+	}
+
+	votes := []models.ExportVote{}
+	for v, voice := range s.votes {
+		if v.nickname == k {
+			votes = append(votes, models.ExportVote{Thread: v.thread, Voice: voice})
+		}
+	}
+	sort.Slice(votes, func(i, j int) bool { return votes[i].Thread < votes[j].Thread })
+	for _, v := range votes {
+		sections[store.ExportVotes] = append(sections[store.ExportVotes], v)
+	}
+	return sections, nil
+}
@@ -0,0 +1,245 @@
+package memory
+
+import (
+	"context"
+	"server/models"
+	"server/store"
+	"sort"
+	"time"
+)
+
+func (s *Store) CreateForum(ctx context.Context, f models.Forum) (models.Forum, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nickname, err := s.canonical(f.User)
+	if err != nil {
+		return f, err
+	}
+	f.User = nickname
+
+	// A sub-forum always sits in the category of its parent.
+	if f.Parent != "" {
+		parent, err := s.forum(f.Parent)
+		if err != nil {
+			return f, err
+		}
+		f.Parent, f.Category = parent.Slug, parent.Category
+	} else if f.Category != "" {
+		c, ok := s.categories[key(f.Category)]
+		if !ok {
+			return f, store.NotFound(store.Category, f.Category)
+		}
+		f.Category = c.Slug
+	}
+
+	if existing, ok := s.forums[key(f.Slug)]; ok {
+		// A soft-deleted forum keeps its slug but must not be shown.
+		if existing.deleted != nil {
+			return models.Forum{}, store.ErrConflict
+		}
+		return existing.Forum, store.ErrConflict
+	}
+
+	f.Posts, f.Threads = 0, 0
+	s.forums[key(f.Slug)] = &forum{
+		Forum: f,
+		users: map[string]models.User{},
+		roles: map[string]models.ForumRole{},
+	}
+	return f, nil
+}
+
+func (s *Store) GetForum(ctx context.Context, slug string) (models.Forum, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, err := s.forum(slug)
+	if err != nil {
+		return models.Forum{}, err
+	}
+	return f.Forum, nil
+}
+
+func (s *Store) GetDeletedForum(ctx context.Context, slug string) (models.Forum, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, ok := s.forums[key(slug)]
+	if !ok || f.deleted == nil {
+		return models.Forum{}, store.NotFound(store.Forum, slug)
+	}
+	return f.Forum, nil
+}
+
+func (s *Store) Forums(ctx context.Context, q store.ForumsQuery) ([]models.Forum, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if q.Owner != "" {
+		owner, err := s.canonical(q.Owner)
+		if err != nil {
+			return nil, err
+		}
+		q.Owner = owner
+	}
+
+	less := func(a, b models.Forum) bool {
+		switch q.Sort {
+		case store.ForumSortTitle:
+			if a.Title != b.Title {
+				return a.Title < b.Title
+			}
+		case store.ForumSortPosts:
+			if a.Posts != b.Posts {
+				return a.Posts < b.Posts
+			}
+		case store.ForumSortThreads:
+			if a.Threads != b.Threads {
+				return a.Threads < b.Threads
+			}
+		}
+		return key(a.Slug) < key(b.Slug)
+	}
+	after := func(a, b models.Forum) bool {
+		if q.Desc {
+			return less(b, a)
+		}
+		return less(a, b)
+	}
+
+	var since *forum
+	if q.Since != "" {
+		var ok bool
+		// Like a keyset query against a missing row, an unknown cursor
+		// matches nothing.
+		if since, ok = s.forums[key(q.Since)]; !ok {
+			return []models.Forum{}, nil
+		}
+	}
+
+	forums := []models.Forum{}
+	for _, f := range s.forums {
+		if f.deleted != nil || q.Owner != "" && f.User != q.Owner {
+			continue
+		}
+		if since != nil && !after(since.Forum, f.Forum) {
+			continue
+		}
+		forums = append(forums, f.Forum)
+	}
+
+	sort.Slice(forums, func(i, j int) bool {
+		return after(forums[i], forums[j])
+	})
+
+	if q.Limit >= 0 && len(forums) > q.Limit {
+		forums = forums[:q.Limit]
+	}
+	return forums, nil
+}
+
+func (s *Store) ForumUsers(ctx context.Context, slug string, q store.UsersQuery) ([]models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, err := s.forum(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	since := key(q.Since)
+	users := []models.User{}
+	for k, u := range f.users {
+		if q.Since != "" && (q.Desc && k >= since || !q.Desc && k <= since) {
+			continue
+		}
+		users = append(users, u)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		if q.Desc {
+			return key(users[i].Nickname) > key(users[j].Nickname)
+		}
+		return key(users[i].Nickname) < key(users[j].Nickname)
+	})
+
+	if q.Limit >= 0 && len(users) > q.Limit {
+		users = users[:q.Limit]
+	}
+	return users, nil
+}
+
+func (s *Store) ForumThreads(ctx context.Context, slug string, q store.ThreadsQuery) ([]models.Thread, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, err := s.forum(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	threads := []models.Thread{}
+	for _, id := range f.threads {
+		t := s.threads[id]
+		if t.deleted != nil {
+			continue
+		}
+		if q.Since != nil && (q.Desc && t.Created.After(*q.Since) || !q.Desc && t.Created.Before(*q.Since)) {
+			continue
+		}
+		threads = append(threads, t.Thread)
+	}
+
+	sort.SliceStable(threads, func(i, j int) bool {
+		if q.Desc {
+			return threads[i].Created.After(threads[j].Created)
+		}
+		return threads[i].Created.Before(threads[j].Created)
+	})
+
+	if q.Limit >= 0 && len(threads) > q.Limit {
+		threads = threads[:q.Limit]
+	}
+	return threads, nil
+}
+
+func (s *Store) UpdateForum(ctx context.Context, slug string, forum models.Forum) (models.Forum, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.forum(slug)
+	if err != nil {
+		return forum, err
+	}
+
+	if forum.User != "" {
+		nickname, err := s.canonical(forum.User)
+		if err != nil {
+			return forum, err
+		}
+		f.User = nickname
+		delete(f.roles, key(nickname))
+	}
+	if forum.Title != "" {
+		f.Title = forum.Title
+	}
+	return f.Forum, nil
+}
+
+func (s *Store) DeleteForum(ctx context.Context, slug string, soft bool) error {
+	if !soft {
+		return s.ClearForum(ctx, slug)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.forum(slug)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	f.deleted = &now
+	return nil
+}
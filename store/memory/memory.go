@@ -0,0 +1,194 @@
+package memory
+
+import (
+	"context"
+	"server/models"
+	"server/store"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store keeps the whole forum in process memory and reproduces what the
+// triggers of the Postgres schema do: post paths, forum counters, thread
+// votes and the forum_users table. Nicknames, emails and slugs compare
+// case-insensitively, like the citext columns.
+type Store struct {
+	mu sync.RWMutex
+
+	users  map[string]*models.User
+	emails map[string]string
+
+	forums     map[string]*forum
+	categories map[string]models.Category
+	threads    map[int]*thread
+	slugs      map[string]int
+	posts      map[int]*post
+	votes      map[vote]int
+
+	// revisions holds the edits of every post by post id.
+	revisions map[int][]models.PostRevision
+
+	// threadSeq, postSeq, revisionSeq and apiKeySeq are the last ids handed
+	// out, like BIGSERIAL.
+	threadSeq   int
+	postSeq     int
+	revisionSeq int
+	apiKeySeq   int
+
+	// passwords holds bcrypt hashes by lower-cased nickname, tokens the
+	// login sessions by token hash.
+	passwords map[string]string
+	tokens    map[string]models.Token
+	apiKeys   map[int]*models.APIKey
+
+	// audit survives Clear, like the audit_log table.
+	audit []models.AuditEntry
+}
+
+type forum struct {
+	models.Forum
+	threads []int
+	// users is forum_users: a copy of every author who posted or opened a
+	// thread here, keyed by the lower-cased nickname.
+	users map[string]models.User
+	roles map[string]models.ForumRole
+	// deleted is set by a soft delete; the forum keeps its rows and slug.
+	deleted *time.Time
+}
+
+type thread struct {
+	models.Thread
+	posts []int
+	// deleted is set by a soft delete; the thread keeps its posts and votes.
+	deleted *time.Time
+}
+
+type post struct {
+	models.Post
+	path []int
+}
+
+type vote struct {
+	thread   int
+	nickname string
+}
+
+var _ store.Store = (*Store)(nil)
+
+func New() *Store {
+	s := &Store{}
+	s.reset()
+	return s
+}
+
+func (s *Store) reset() {
+	s.users = map[string]*models.User{}
+	s.emails = map[string]string{}
+	s.forums = map[string]*forum{}
+	s.categories = map[string]models.Category{}
+	s.threads = map[int]*thread{}
+	s.slugs = map[string]int{}
+	s.posts = map[int]*post{}
+	s.revisions = map[int][]models.PostRevision{}
+	s.threadSeq, s.postSeq, s.revisionSeq = 0, 0, 0
+	s.votes = map[vote]int{}
+	s.passwords = map[string]string{}
+	s.tokens = map[string]models.Token{}
+	s.apiKeys = map[int]*models.APIKey{}
+	s.apiKeySeq = 0
+}
+
+func key(s string) string {
+	return strings.ToLower(s)
+}
+
+func (s *Store) Clear(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reset()
+	return nil
+}
+
+func (s *Store) ClearForum(ctx context.Context, slug string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.forums[key(slug)]
+	if !ok {
+		return store.NotFound(store.Forum, slug)
+	}
+
+	for _, id := range f.threads {
+		s.deleteThread(s.threads[id])
+	}
+	// Sub-forums move up to the parent of the deleted forum.
+	for _, child := range s.forums {
+		if key(child.Parent) == key(f.Slug) {
+			child.Parent = f.Parent
+		}
+	}
+	delete(s.forums, key(slug))
+	return nil
+}
+
+// forum resolves a slug to a forum that is not soft-deleted; the caller
+// holds the lock.
+func (s *Store) forum(slug string) (*forum, error) {
+	f, ok := s.forums[key(slug)]
+	if !ok || f.deleted != nil {
+		return nil, store.NotFound(store.Forum, slug)
+	}
+	return f, nil
+}
+
+// deleteThread removes the thread with its posts and votes; the caller
+// holds the lock and fixes the forum counters.
+func (s *Store) deleteThread(t *thread) {
+	for _, id := range t.posts {
+		delete(s.posts, id)
+		delete(s.revisions, id)
+	}
+	for v := range s.votes {
+		if v.thread == t.Id {
+			delete(s.votes, v)
+		}
+	}
+	if t.Slug != "" {
+		delete(s.slugs, key(t.Slug))
+	}
+	delete(s.threads, t.Id)
+}
+
+func (s *Store) Audit(ctx context.Context, e models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.Id = len(s.audit) + 1
+	s.audit = append(s.audit, e)
+	return nil
+}
+
+func (s *Store) AuditLog(ctx context.Context, limit int) ([]models.AuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := []models.AuditEntry{}
+	for i := len(s.audit) - 1; i >= 0 && len(entries) != limit; i-- {
+		entries = append(entries, s.audit[i])
+	}
+	return entries, nil
+}
+
+func (s *Store) Status(ctx context.Context) (models.Status, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return models.Status{
+		User:   len(s.users),
+		Forum:  len(s.forums),
+		Thread: len(s.threads),
+		Post:   len(s.posts),
+	}, nil
+}
@@ -0,0 +1,367 @@
+package memory
+
+import (
+	"context"
+	"server/models"
+	"server/store"
+	"sort"
+	"strconv"
+	"time"
+)
+
+func (s *Store) CreatePosts(ctx context.Context, ref store.ThreadRef, posts []models.Post) ([]models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.thread(ref)
+	if err != nil {
+		return nil, err
+	}
+	f := s.forums[key(t.Forum)]
+
+	if len(posts) == 0 {
+		return []models.Post{}, nil
+	}
+
+	// Like the Postgres store, only the first parent is checked.
+	if posts[0].Parent != 0 {
+		parent := s.post(posts[0].Parent)
+		if parent == nil || parent.Thread != t.Id {
+			return nil, store.ErrParentConflict
+		}
+	}
+
+	for _, item := range posts {
+		if _, err := s.canonical(item.Author); err != nil {
+			return nil, err
+		}
+	}
+
+	created := time.Now().Truncate(time.Microsecond)
+
+	result := make([]models.Post, 0, len(posts))
+	for _, item := range posts {
+		s.postSeq++
+		p := &post{Post: models.Post{
+			Id:      s.postSeq,
+			Parent:  item.Parent,
+			Author:  item.Author,
+			Message: item.Message,
+			Forum:   t.Forum,
+			Thread:  t.Id,
+			Created: created,
+		}}
+		if parent := s.post(item.Parent); parent != nil {
+			p.path = append(p.path, parent.path...)
+		}
+		p.path = append(p.path, p.Id)
+
+		s.posts[p.Id] = p
+		t.posts = append(t.posts, p.Id)
+		f.Posts++
+		s.joinForum(f, item.Author)
+
+		result = append(result, p.Post)
+	}
+
+	return result, nil
+}
+
+// post returns nil when there is no post with the id; the caller holds the lock.
+func (s *Store) post(id int) *post {
+	return s.posts[id]
+}
+
+// public is the post as the API shows it. Like the Postgres store, a
+// tombstone keeps its author for the per-user listings and hides it here.
+func (p *post) public() models.Post {
+	result := p.Post
+	if result.IsDeleted {
+		result.Author = ""
+	}
+	return result
+}
+
+func (s *Store) GetPost(ctx context.Context, id int) (models.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, _, err := s.livePost(id)
+	if err != nil {
+		return models.Post{}, err
+	}
+	return p.public(), nil
+}
+
+func (s *Store) UpdatePost(ctx context.Context, id int, message, editor string) (models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updatePost(id, message, editor)
+}
+
+// updatePost keeps the replaced message as a revision when the text
+// changes; the caller holds the lock.
+func (s *Store) updatePost(id int, message, editor string) (models.Post, error) {
+	p, _, err := s.livePost(id)
+	if err != nil {
+		return models.Post{}, err
+	}
+	if p.IsDeleted {
+		return models.Post{}, store.NotFound(store.Post, strconv.Itoa(id))
+	}
+
+	if message != "" && message != p.Message {
+		s.revisionSeq++
+		s.revisions[id] = append(s.revisions[id], models.PostRevision{
+			Id:      s.revisionSeq,
+			Post:    id,
+			Editor:  editor,
+			Message: p.Message,
+			Created: time.Now().Truncate(time.Microsecond),
+		})
+	}
+
+	// Same rule as the updatePost statement: an empty or unchanged message
+	// clears isEdited.
+	p.IsEdited = message != "" && message != p.Message
+	if message != "" {
+		p.Message = message
+	}
+	return p.Post, nil
+}
+
+func (s *Store) ThreadPosts(ctx context.Context, ref store.ThreadRef, q store.PostsQuery) ([]models.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, err := s.thread(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make([]*post, 0, len(t.posts))
+	for _, id := range t.posts {
+		posts = append(posts, s.posts[id])
+	}
+
+	var since *post
+	if q.Since != 0 {
+		// The tree statements compare against the since post's path, which
+		// is NULL and matches nothing when the post does not exist.
+		if since = s.post(q.Since); since == nil {
+			if q.Sort == "tree" || q.Sort == "parent_tree" {
+				return []models.Post{}, nil
+			}
+			since = &post{Post: models.Post{Id: q.Since}}
+		}
+	}
+
+	switch q.Sort {
+	case "tree":
+		posts = treePosts(posts, since, q)
+	case "parent_tree":
+		posts = parentTreePosts(posts, since, q)
+	default:
+		posts = flatPosts(posts, since, q)
+	}
+
+	result := make([]models.Post, 0, len(posts))
+	for _, p := range posts {
+		result = append(result, p.public())
+	}
+	return result, nil
+}
+
+func flatPosts(posts []*post, since *post, q store.PostsQuery) []*post {
+	var result []*post
+	for _, p := range posts {
+		if since != nil && (q.Desc && p.Id >= since.Id || !q.Desc && p.Id <= since.Id) {
+			continue
+		}
+		result = append(result, p)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if q.Desc {
+			return result[i].Id > result[j].Id
+		}
+		return result[i].Id < result[j].Id
+	})
+
+	return limit(result, q.Limit)
+}
+
+func treePosts(posts []*post, since *post, q store.PostsQuery) []*post {
+	var result []*post
+	for _, p := range posts {
+		if since != nil {
+			c := comparePaths(p.path, since.path)
+			if q.Desc && c >= 0 || !q.Desc && c <= 0 {
+				continue
+			}
+		}
+		result = append(result, p)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		c := comparePaths(result[i].path, result[j].path)
+		if c == 0 {
+			c = result[i].Id - result[j].Id
+		}
+		if q.Desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	return limit(result, q.Limit)
+}
+
+// parentTreePosts pages by root posts and returns every reply of each root.
+func parentTreePosts(posts []*post, since *post, q store.PostsQuery) []*post {
+	var roots []int
+	for _, p := range posts {
+		if p.Parent != 0 {
+			continue
+		}
+		if since != nil && (q.Desc && p.Id >= since.path[0] || !q.Desc && p.Id <= since.path[0]) {
+			continue
+		}
+		roots = append(roots, p.Id)
+	}
+
+	sort.Slice(roots, func(i, j int) bool {
+		if q.Desc {
+			return roots[i] > roots[j]
+		}
+		return roots[i] < roots[j]
+	})
+	if q.Limit >= 0 && len(roots) > q.Limit {
+		roots = roots[:q.Limit]
+	}
+
+	order := make(map[int]int, len(roots))
+	for i, id := range roots {
+		order[id] = i
+	}
+
+	var result []*post
+	for _, p := range posts {
+		if _, ok := order[p.path[0]]; ok {
+			result = append(result, p)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		ri, rj := order[result[i].path[0]], order[result[j].path[0]]
+		if ri != rj {
+			return ri < rj
+		}
+		c := comparePaths(result[i].path, result[j].path)
+		if c == 0 {
+			return result[i].Id < result[j].Id
+		}
+		return c < 0
+	})
+
+	return result
+}
+
+// comparePaths orders materialized paths like Postgres compares BIGINT[].
+func comparePaths(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return len(a) - len(b)
+}
+
+func limit(posts []*post, n int) []*post {
+	if n >= 0 && len(posts) > n {
+		return posts[:n]
+	}
+	return posts
+}
+
+// livePost returns the post unless it, its thread or its forum is deleted;
+// the caller holds the lock.
+func (s *Store) livePost(id int) (*post, *forum, error) {
+	p := s.post(id)
+	if p == nil || s.threads[p.Thread].deleted != nil {
+		return nil, nil, store.NotFound(store.Post, strconv.Itoa(id))
+	}
+	f, err := s.forum(p.Forum)
+	if err != nil {
+		return nil, nil, store.NotFound(store.Post, strconv.Itoa(id))
+	}
+	return p, f, nil
+}
+
+func (s *Store) DeletePost(ctx context.Context, id int) (models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, _, err := s.livePost(id)
+	if err != nil {
+		return models.Post{}, err
+	}
+	if p.IsDeleted {
+		return models.Post{}, store.NotFound(store.Post, strconv.Itoa(id))
+	}
+
+	p.Message, p.IsEdited, p.IsDeleted = models.DeletedMessage, false, true
+	// The revisions hold the deleted text too.
+	delete(s.revisions, id)
+	return p.public(), nil
+}
+
+func (s *Store) DeletePostTree(ctx context.Context, id int) (models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	root, f, err := s.livePost(id)
+	if err != nil {
+		return models.Post{}, err
+	}
+
+	t := s.threads[root.Thread]
+	kept := t.posts[:0]
+	for _, pid := range t.posts {
+		p := s.posts[pid]
+		if len(p.path) >= len(root.path) && comparePaths(p.path[:len(root.path)], root.path) == 0 {
+			delete(s.posts, pid)
+			delete(s.revisions, pid)
+			f.Posts--
+			continue
+		}
+		kept = append(kept, pid)
+	}
+	t.posts = kept
+
+	s.pruneForum(f)
+	return root.public(), nil
+}
+
+func (s *Store) PostRevisions(ctx context.Context, id int) ([]models.PostRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, _, err := s.livePost(id); err != nil {
+		return nil, err
+	}
+	return append([]models.PostRevision{}, s.revisions[id]...), nil
+}
+
+func (s *Store) RollbackPost(ctx context.Context, id, revision int, editor string) (models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range s.revisions[id] {
+		if r.Id == revision {
+			return s.updatePost(id, r.Message, editor)
+		}
+	}
+	return models.Post{}, store.NotFound(store.Revision, strconv.Itoa(revision))
+}
@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"context"
+	"server/models"
+	"server/store"
+	"sort"
+)
+
+func (s *Store) ForumRole(ctx context.Context, forum, nickname string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, err := s.forum(forum)
+	if err != nil {
+		return "", nil
+	}
+	return f.roles[key(nickname)].Role, nil
+}
+
+func (s *Store) ForumRoles(ctx context.Context, slug string) ([]models.ForumRole, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, err := s.forum(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := []models.ForumRole{}
+	for _, r := range f.roles {
+		roles = append(roles, r)
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		return key(roles[i].Nickname) < key(roles[j].Nickname)
+	})
+	return roles, nil
+}
+
+func (s *Store) SetForumRole(ctx context.Context, role models.ForumRole) (models.ForumRole, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.forum(role.Forum)
+	if err != nil {
+		return role, err
+	}
+	role.Forum = f.Slug
+
+	nickname, err := s.canonical(role.Nickname)
+	if err != nil {
+		return role, err
+	}
+	role.Nickname = nickname
+
+	f.roles[key(nickname)] = role
+	return role, nil
+}
+
+func (s *Store) DeleteForumRole(ctx context.Context, forum, nickname string) (models.ForumRole, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.forum(forum)
+	if err != nil {
+		return models.ForumRole{}, err
+	}
+	role, ok := f.roles[key(nickname)]
+	if !ok {
+		return role, store.NotFound(store.Role, nickname)
+	}
+	delete(f.roles, key(nickname))
+	return role, nil
+}
@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"server/models"
+	"server/store"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// similarityThreshold is the default pg_trgm.similarity_threshold used by
+// the % operator.
+const similarityThreshold = 0.3
+
+func (s *Store) SearchUsers(ctx context.Context, q store.UserSearchQuery) ([]models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var members map[string]models.User
+	if q.Forum != "" {
+		f, err := s.forum(q.Forum)
+		if err != nil {
+			return nil, err
+		}
+		members = f.users
+	}
+
+	query := strings.ToLower(q.Query)
+	grams := trigrams(query)
+
+	type match struct {
+		user  models.User
+		class int
+	}
+	matches := []match{}
+	for k, u := range s.users {
+		if members != nil {
+			if _, ok := members[k]; !ok {
+				continue
+			}
+		}
+		nickname, fullname := strings.ToLower(u.Nickname), strings.ToLower(u.Fullname)
+		switch {
+		case strings.HasPrefix(nickname, query) || strings.HasPrefix(fullname, query):
+			matches = append(matches, match{*u, 0})
+		case similarity(grams, trigrams(nickname)) > similarityThreshold ||
+			similarity(grams, trigrams(fullname)) > similarityThreshold:
+			matches = append(matches, match{*u, 1})
+		}
+	}
+
+	less := func(a, b match) bool {
+		if a.class != b.class {
+			return a.class < b.class
+		}
+		return key(a.user.Nickname) < key(b.user.Nickname)
+	}
+	sort.Slice(matches, func(i, j int) bool { return less(matches[i], matches[j]) })
+
+	start := 0
+	if q.Since != "" {
+		// Like the keyset query, the cursor takes its class from the users,
+		// so it pages even when that user is no longer among the matches.
+		since := match{user: models.User{Nickname: q.Since}, class: 1}
+		if strings.HasPrefix(key(q.Since), query) {
+			since.class = 0
+		} else if u, ok := s.users[key(q.Since)]; ok && strings.HasPrefix(strings.ToLower(u.Fullname), query) {
+			since.class = 0
+		}
+		start = sort.Search(len(matches), func(i int) bool { return less(since, matches[i]) })
+	}
+
+	users := []models.User{}
+	for _, m := range matches[start:] {
+		if q.Limit >= 0 && len(users) == q.Limit {
+			break
+		}
+		users = append(users, m.user)
+	}
+	return users, nil
+}
+
+// trigrams splits s into words of letters and digits and returns their
+// trigrams the way pg_trgm does: every word padded with two spaces in front
+// and one behind.
+func trigrams(s string) map[string]bool {
+	grams := map[string]bool{}
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		r := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			grams[string(r[i:i+3])] = true
+		}
+	}
+	return grams
+}
+
+// similarity is the share of trigrams the two sets have in common.
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for g := range a {
+		if b[g] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
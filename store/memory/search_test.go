@@ -0,0 +1,29 @@
+package memory
+
+import (
+	"math"
+	"testing"
+)
+
+// The expected values are what pg_trgm's similarity() returns.
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"word", "word", 1},
+		{"word", "two words", 4.0 / 11},
+		{"sparow", "sparrow", 6.0 / 9},
+		{"sparow", "sparrowhawk", 5.0 / 14},
+		{"ёжик", "ежик", 2.0 / 8},
+		{"abc", "xyz", 0},
+		{"", "abc", 0},
+	}
+
+	for _, tt := range tests {
+		got := similarity(trigrams(tt.a), trigrams(tt.b))
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("similarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
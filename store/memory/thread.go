@@ -0,0 +1,182 @@
+package memory
+
+import (
+	"context"
+	"server/models"
+	"server/store"
+	"time"
+)
+
+func (s *Store) CreateThread(ctx context.Context, t models.Thread) (models.Thread, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	author, err := s.canonical(t.Author)
+	if err != nil {
+		return t, err
+	}
+	t.Author = author
+
+	f, err := s.forum(t.Forum)
+	if err != nil {
+		return t, err
+	}
+	t.Forum = f.Slug
+
+	if t.Slug != "" {
+		if id, ok := s.slugs[key(t.Slug)]; ok {
+			// A soft-deleted thread keeps its slug but must not be shown.
+			existing, err := s.thread(store.ThreadRef{ID: id})
+			if err != nil {
+				return models.Thread{}, store.ErrConflict
+			}
+			return existing.Thread, store.ErrConflict
+		}
+	}
+
+	s.threadSeq++
+	t.Id = s.threadSeq
+	s.threads[t.Id] = &thread{Thread: t}
+	if t.Slug != "" {
+		s.slugs[key(t.Slug)] = t.Id
+	}
+
+	f.Threads++
+	f.threads = append(f.threads, t.Id)
+	s.joinForum(f, t.Author)
+
+	return t, nil
+}
+
+// thread resolves a slug or id to a thread that is not soft-deleted and not
+// in a soft-deleted forum; the caller holds the lock.
+func (s *Store) thread(ref store.ThreadRef) (*thread, error) {
+	t, err := s.anyThread(ref)
+	if err != nil {
+		return nil, err
+	}
+	if t.deleted != nil {
+		return nil, store.NotFound(store.Thread, ref.String())
+	}
+	// The threads of a soft-deleted forum are hidden with it.
+	if _, err := s.forum(t.Forum); err != nil {
+		return nil, store.NotFound(store.Thread, ref.String())
+	}
+	return t, nil
+}
+
+// anyThread is thread with the soft-deleted ones included.
+func (s *Store) anyThread(ref store.ThreadRef) (*thread, error) {
+	id := ref.ID
+	if !ref.IsID() {
+		var ok bool
+		if id, ok = s.slugs[key(ref.Slug)]; !ok {
+			return nil, store.NotFound(store.Thread, ref.String())
+		}
+	}
+	t, ok := s.threads[id]
+	if !ok {
+		return nil, store.NotFound(store.Thread, ref.String())
+	}
+	return t, nil
+}
+
+func (s *Store) GetThread(ctx context.Context, ref store.ThreadRef) (models.Thread, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, err := s.thread(ref)
+	if err != nil {
+		return models.Thread{}, err
+	}
+	return t.Thread, nil
+}
+
+func (s *Store) UpdateThread(ctx context.Context, ref store.ThreadRef, thread models.Thread) (models.Thread, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.thread(ref)
+	if err != nil {
+		return models.Thread{}, err
+	}
+
+	if thread.Title != "" {
+		t.Title = thread.Title
+	}
+	if thread.Message != "" {
+		t.Message = thread.Message
+	}
+	return t.Thread, nil
+}
+
+func (s *Store) ThreadForum(ctx context.Context, ref store.ThreadRef) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, err := s.anyThread(ref)
+	if err != nil {
+		return "", err
+	}
+	return t.Forum, nil
+}
+
+func (s *Store) DeleteThread(ctx context.Context, ref store.ThreadRef, soft bool) (models.Thread, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.anyThread(ref)
+	if err != nil {
+		return models.Thread{}, err
+	}
+	if soft && t.deleted != nil {
+		return t.Thread, store.NotFound(store.Thread, ref.String())
+	}
+
+	f := s.forums[key(t.Forum)]
+	// A soft-deleted thread already left the counters.
+	if t.deleted == nil {
+		f.Threads--
+		f.Posts -= len(t.posts)
+	}
+
+	if soft {
+		now := time.Now()
+		t.deleted = &now
+	} else {
+		s.deleteThread(t)
+		for i, id := range f.threads {
+			if id == t.Id {
+				f.threads = append(f.threads[:i], f.threads[i+1:]...)
+				break
+			}
+		}
+	}
+	s.pruneForum(f)
+	return t.Thread, nil
+}
+
+func (s *Store) RestoreThread(ctx context.Context, ref store.ThreadRef) (models.Thread, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.anyThread(ref)
+	if err != nil {
+		return models.Thread{}, err
+	}
+	if t.deleted == nil {
+		return t.Thread, nil
+	}
+
+	t.deleted = nil
+	f := s.forums[key(t.Forum)]
+	f.Threads++
+	f.Posts += len(t.posts)
+	s.joinForum(f, t.Author)
+	for _, id := range t.posts {
+		if p := s.posts[id]; !p.IsDeleted {
+			s.joinForum(f, p.Author)
+		}
+	}
+	return t.Thread, nil
+}
@@ -0,0 +1,191 @@
+package memory
+
+import (
+	"context"
+	"server/models"
+	"server/store"
+)
+
+func (s *Store) CreateUser(ctx context.Context, user models.User, hash string) ([]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var users []models.User
+	if u, ok := s.users[key(user.Nickname)]; ok {
+		users = append(users, *u)
+	}
+	if nickname, ok := s.emails[key(user.Email)]; ok && nickname != key(user.Nickname) {
+		users = append(users, *s.users[nickname])
+	}
+	if users != nil {
+		return users, store.ErrConflict
+	}
+
+	u := user
+	s.users[key(user.Nickname)] = &u
+	s.emails[key(user.Email)] = key(user.Nickname)
+	if hash != "" {
+		s.passwords[key(user.Nickname)] = hash
+	}
+	return nil, nil
+}
+
+func (s *Store) GetUser(ctx context.Context, nickname string) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[key(nickname)]
+	if !ok {
+		return models.User{}, store.NotFound(store.User, nickname)
+	}
+	return *u, nil
+}
+
+func (s *Store) UpdateUser(ctx context.Context, user models.User) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[key(user.Nickname)]
+	if !ok {
+		return user, store.NotFound(store.User, user.Nickname)
+	}
+
+	if user.Email != "" {
+		if owner, ok := s.emails[key(user.Email)]; ok && owner != key(u.Nickname) {
+			return user, store.ErrConflict
+		}
+		delete(s.emails, key(u.Email))
+		s.emails[key(user.Email)] = key(u.Nickname)
+		u.Email = user.Email
+	}
+	if user.Fullname != "" {
+		u.Fullname = user.Fullname
+	}
+	if user.About != "" {
+		u.About = user.About
+	}
+
+	// Like the forum_users_sync trigger, refresh the forum_users copies.
+	for _, f := range s.forums {
+		if _, ok := f.users[key(u.Nickname)]; ok {
+			f.users[key(u.Nickname)] = *u
+		}
+	}
+
+	return *u, nil
+}
+
+// canonical returns the nickname as it was registered, like checkUser.
+func (s *Store) canonical(nickname string) (string, error) {
+	u, ok := s.users[key(nickname)]
+	if !ok {
+		return nickname, store.NotFound(store.User, nickname)
+	}
+	return u.Nickname, nil
+}
+
+// joinForum is the forum_users part of the thread and post triggers.
+func (s *Store) joinForum(f *forum, nickname string) {
+	k := key(nickname)
+	if _, ok := f.users[k]; ok {
+		return
+	}
+	if u, ok := s.users[k]; ok {
+		f.users[k] = *u
+	}
+}
+
+// pruneForum drops the forum_users rows of users left without a thread or
+// post in the forum that is not deleted.
+func (s *Store) pruneForum(f *forum) {
+	active := map[string]bool{}
+	for _, id := range f.threads {
+		t := s.threads[id]
+		if t.deleted != nil {
+			continue
+		}
+		active[key(t.Author)] = true
+		for _, pid := range t.posts {
+			if p := s.posts[pid]; !p.IsDeleted {
+				active[key(p.Author)] = true
+			}
+		}
+	}
+	for k := range f.users {
+		if !active[k] {
+			delete(f.users, k)
+		}
+	}
+}
+
+func (s *Store) AnonymizeUser(ctx context.Context, nickname string, ghost models.User) (models.UserDeletion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := models.UserDeletion{Ghost: ghost.Nickname}
+	k := key(nickname)
+	u, ok := s.users[k]
+	if !ok {
+		return result, store.NotFound(store.User, nickname)
+	}
+
+	g := ghost
+	s.users[key(g.Nickname)] = &g
+	s.emails[key(g.Email)] = key(g.Nickname)
+
+	for v, voice := range s.votes {
+		if v.nickname == k {
+			if t, ok := s.threads[v.thread]; ok {
+				t.Votes -= voice
+			}
+			delete(s.votes, v)
+			result.Votes++
+		}
+	}
+
+	for _, f := range s.forums {
+		if key(f.User) == k {
+			f.User = g.Nickname
+			result.Forums++
+		}
+		if _, ok := f.users[k]; ok {
+			delete(f.users, k)
+			f.users[key(g.Nickname)] = g
+		}
+		delete(f.roles, k)
+	}
+	for _, t := range s.threads {
+		if key(t.Author) == k {
+			t.Author = g.Nickname
+			result.Threads++
+		}
+	}
+	for _, p := range s.posts {
+		if key(p.Author) == k {
+			p.Author = g.Nickname
+			result.Posts++
+		}
+	}
+	for id, revisions := range s.revisions {
+		for i := range revisions {
+			if key(revisions[i].Editor) == k {
+				s.revisions[id][i].Editor = g.Nickname
+			}
+		}
+	}
+
+	for id, a := range s.apiKeys {
+		if key(a.Nickname) == k {
+			delete(s.apiKeys, id)
+		}
+	}
+	for hash, token := range s.tokens {
+		if key(token.Nickname) == k {
+			delete(s.tokens, hash)
+		}
+	}
+	delete(s.passwords, k)
+	delete(s.emails, key(u.Email))
+	delete(s.users, k)
+	return result, nil
+}
@@ -0,0 +1,27 @@
+package memory
+
+import (
+	"context"
+	"server/models"
+	"server/store"
+)
+
+func (s *Store) Vote(ctx context.Context, ref store.ThreadRef, v models.Vote) (models.Thread, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.canonical(v.Nickname); err != nil {
+		return models.Thread{}, err
+	}
+
+	t, err := s.thread(ref)
+	if err != nil {
+		return models.Thread{}, err
+	}
+
+	k := vote{thread: t.Id, nickname: key(v.Nickname)}
+	t.Votes += v.Voice - s.votes[k]
+	s.votes[k] = v.Voice
+
+	return t.Thread, nil
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"server/database"
+	"server/models"
+	"server/store"
+)
+
+// activity canonicalizes the nickname and forum of q and returns the
+// statement to run with its arguments.
+func (s *Store) activity(ctx context.Context, statement, nickname string, q store.ActivityQuery) (string, []interface{}, error) {
+	err := s.db.QueryRow(ctx, "checkUser", nickname).Scan(&nickname)
+	if err != nil {
+		return "", nil, notFound("checkUser", err, store.User, nickname)
+	}
+	if q.Forum != "" {
+		if q.Forum, err = s.checkForum(ctx, q.Forum); err != nil {
+			return "", nil, err
+		}
+	}
+	if q.Desc {
+		statement += "Desc"
+	}
+	return statement, []interface{}{nickname, q.Limit, q.Since, q.Forum, q.SinceBound()}, nil
+}
+
+func (s *Store) UserThreads(ctx context.Context, nickname string, q store.ActivityQuery) ([]models.Thread, error) {
+	statement, args, err := s.activity(ctx, "selectUserThreads", nickname, q)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(ctx, statement, args...)
+	if err != nil {
+		return nil, database.Wrap(statement, err)
+	}
+	defer rows.Close()
+
+	threads := []models.Thread{}
+	for rows.Next() {
+		t, err := scanThread(rows)
+		if err != nil {
+			return nil, database.Wrap(statement, err)
+		}
+		threads = append(threads, t)
+	}
+
+	return threads, database.Wrap(statement, rows.Err())
+}
+
+func (s *Store) UserPosts(ctx context.Context, nickname string, q store.ActivityQuery) ([]models.Post, error) {
+	statement, args, err := s.activity(ctx, "selectUserPosts", nickname, q)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(ctx, statement, args...)
+	if err != nil {
+		return nil, database.Wrap(statement, err)
+	}
+	defer rows.Close()
+
+	posts := []models.Post{}
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, database.Wrap(statement, err)
+		}
+		posts = append(posts, p)
+	}
+
+	return posts, database.Wrap(statement, rows.Err())
+}
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"server/database"
+	"server/models"
+	"server/store"
+	"strconv"
+	"time"
+)
+
+const apiKeyColumns = "id, hash, nickname, name, scopes, created, expires, last_used, revoked"
+
+func scanAPIKey(row scanner) (models.APIKey, error) {
+	k := models.APIKey{}
+	err := row.Scan(&k.Id, &k.Hash, &k.Nickname, &k.Name, &k.Scopes, &k.Created, &k.Expires, &k.LastUsed, &k.Revoked)
+	return k, err
+}
+
+func (s *Store) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return key, database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("checkUser", key.Nickname).Scan(&key.Nickname)
+	if err != nil {
+		return key, notFound("checkUser", err, store.User, key.Nickname)
+	}
+
+	err = tx.QueryRow("insertAPIKey",
+		key.Hash,
+		key.Nickname,
+		key.Name,
+		key.Scopes,
+		key.Created,
+		key.Expires).Scan(&key.Id)
+	if err != nil {
+		return key, database.Wrap("insertAPIKey", err)
+	}
+
+	return key, database.Wrap("commit", tx.Commit())
+}
+
+func (s *Store) APIKeys(ctx context.Context, nickname string) ([]models.APIKey, error) {
+	err := s.db.QueryRow(ctx, "checkUser", nickname).Scan(&nickname)
+	if err != nil {
+		return nil, notFound("checkUser", err, store.User, nickname)
+	}
+
+	rows, err := s.db.Query(ctx, "selectAPIKeys", nickname)
+	if err != nil {
+		return nil, database.Wrap("selectAPIKeys", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, database.Wrap("selectAPIKeys", err)
+		}
+		keys = append(keys, k)
+	}
+
+	return keys, database.Wrap("selectAPIKeys", rows.Err())
+}
+
+func (s *Store) GetAPIKey(ctx context.Context, hash string) (models.APIKey, error) {
+	key, err := scanAPIKey(s.db.QueryRow(ctx, "selectAPIKey", hash))
+	if err != nil {
+		return key, notFound("selectAPIKey", err, store.APIKey, hash)
+	}
+	return key, nil
+}
+
+func (s *Store) RevokeAPIKey(ctx context.Context, nickname string, id int) (models.APIKey, error) {
+	key, err := scanAPIKey(s.db.QueryRow(ctx, "revokeAPIKey", nickname, id, time.Now()))
+	if err != nil {
+		return key, notFound("revokeAPIKey", err, store.APIKey, strconv.Itoa(id))
+	}
+	return key, nil
+}
+
+func (s *Store) TouchAPIKey(ctx context.Context, id int, at time.Time) error {
+	_, err := s.db.Exec(ctx, "touchAPIKey", id, at)
+	return database.Wrap("touchAPIKey", err)
+}
@@ -0,0 +1,32 @@
+package postgres
+
+import (
+	"context"
+	"server/database"
+	"server/models"
+)
+
+func (s *Store) Audit(ctx context.Context, e models.AuditEntry) error {
+	_, err := s.db.Exec(ctx, "insertAudit", e.Created, e.Action, e.Scope, e.Actor, e.RequestID, e.Remote, e.Result)
+	return database.Wrap("insertAudit", err)
+}
+
+func (s *Store) AuditLog(ctx context.Context, limit int) ([]models.AuditEntry, error) {
+	rows, err := s.db.Query(ctx, "selectAudit", limit)
+	if err != nil {
+		return nil, database.Wrap("selectAudit", err)
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		e := models.AuditEntry{}
+		err := rows.Scan(&e.Id, &e.Created, &e.Action, &e.Scope, &e.Actor, &e.RequestID, &e.Remote, &e.Result)
+		if err != nil {
+			return nil, database.Wrap("selectAudit", err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, database.Wrap("selectAudit", rows.Err())
+}
@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"context"
+	"server/database"
+	"server/models"
+	"server/store"
+)
+
+func (s *Store) PasswordHash(ctx context.Context, nickname string) (string, string, error) {
+	var hash string
+	err := s.db.QueryRow(ctx, "selectCredentials", nickname).Scan(&nickname, &hash)
+	if err != nil {
+		return nickname, "", notFound("selectCredentials", err, store.User, nickname)
+	}
+	return nickname, hash, nil
+}
+
+func (s *Store) CreateToken(ctx context.Context, token models.Token) error {
+	_, err := s.db.Exec(ctx, "insertToken", token.Token, token.Nickname, token.Created, token.Expires)
+	return database.Wrap("insertToken", err)
+}
+
+func (s *Store) GetToken(ctx context.Context, hash string) (models.Token, error) {
+	token := models.Token{}
+	err := s.db.QueryRow(ctx, "selectToken", hash).Scan(&token.Token, &token.Nickname, &token.Created, &token.Expires)
+	if err != nil {
+		return token, notFound("selectToken", err, store.Token, hash)
+	}
+	return token, nil
+}
+
+func (s *Store) DeleteToken(ctx context.Context, hash string) error {
+	_, err := s.db.Exec(ctx, "deleteToken", hash)
+	return database.Wrap("deleteToken", err)
+}
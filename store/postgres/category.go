@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"server/database"
+	"server/models"
+	"server/store"
+)
+
+func scanCategory(row scanner) (models.Category, error) {
+	c := models.Category{}
+	err := row.Scan(&c.Slug, &c.Title, &c.Position)
+	return c, err
+}
+
+func (s *Store) CreateCategory(ctx context.Context, category models.Category) (models.Category, error) {
+	_, err := s.db.Exec(ctx, "insertCategory", category.Slug, category.Title, category.Position)
+	if isUniqueViolation(err) {
+		existing, err := scanCategory(s.db.QueryRow(ctx, "selectCategory", category.Slug))
+		if err != nil {
+			return category, database.Wrap("selectCategory", err)
+		}
+		return existing, store.ErrConflict
+	}
+	if err != nil {
+		return category, database.Wrap("insertCategory", err)
+	}
+	return category, nil
+}
+
+func (s *Store) ForumTree(ctx context.Context) (models.ForumTree, error) {
+	rows, err := s.db.Query(ctx, "selectCategories")
+	if err != nil {
+		return models.ForumTree{}, database.Wrap("selectCategories", err)
+	}
+	categories := []models.Category{}
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			rows.Close()
+			return models.ForumTree{}, database.Wrap("selectCategories", err)
+		}
+		categories = append(categories, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.ForumTree{}, database.Wrap("selectCategories", err)
+	}
+
+	rows, err = s.db.Query(ctx, "selectForumTree")
+	if err != nil {
+		return models.ForumTree{}, database.Wrap("selectForumTree", err)
+	}
+	defer rows.Close()
+
+	forums := []models.Forum{}
+	for rows.Next() {
+		f, err := scanForum(rows)
+		if err != nil {
+			return models.ForumTree{}, database.Wrap("selectForumTree", err)
+		}
+		forums = append(forums, f)
+	}
+	if err := rows.Err(); err != nil {
+		return models.ForumTree{}, database.Wrap("selectForumTree", err)
+	}
+
+	return store.BuildForumTree(categories, forums), nil
+}
+
+func (s *Store) MoveForum(ctx context.Context, slug string, move models.ForumMove) (models.Forum, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return models.Forum{}, database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("checkForum", slug).Scan(&slug)
+	if err != nil {
+		return models.Forum{}, notFound("checkForum", err, store.Forum, slug)
+	}
+
+	// A sub-forum always sits in the category of its parent.
+	if move.Parent != "" {
+		err = tx.QueryRow("checkParentForum", move.Parent).Scan(&move.Parent, &move.Category)
+		if err != nil {
+			return models.Forum{}, notFound("checkParentForum", err, store.Forum, move.Parent)
+		}
+		var cycle bool
+		if err := tx.QueryRow("isSubForum", slug, move.Parent).Scan(&cycle); err != nil {
+			return models.Forum{}, database.Wrap("isSubForum", err)
+		}
+		if cycle {
+			return models.Forum{}, store.ErrConflict
+		}
+	} else if move.Category != "" {
+		err = tx.QueryRow("checkCategory", move.Category).Scan(&move.Category)
+		if err != nil {
+			return models.Forum{}, notFound("checkCategory", err, store.Category, move.Category)
+		}
+	}
+
+	forum, err := scanForum(tx.QueryRow("moveForum", slug, move.Parent, move.Category, move.Position))
+	if err != nil {
+		return forum, notFound("moveForum", err, store.Forum, slug)
+	}
+	if _, err := tx.Exec("moveSubForums", forum.Slug, forum.Category); err != nil {
+		return forum, database.Wrap("moveSubForums", err)
+	}
+
+	return forum, database.Wrap("commit", tx.Commit())
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"server/database"
+	"server/models"
+	"server/store"
+
+	"github.com/jackc/pgx"
+)
+
+// ExportUser reads every section from one read-only snapshot, so records
+// written while the export streams cannot make the sections disagree.
+func (s *Store) ExportUser(ctx context.Context, nickname string, emit func(section string, record interface{}) error) error {
+	tx, err := s.db.BeginTx(ctx, &pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	user, err := scanUser(tx.QueryRow("selectUser", nickname))
+	if err != nil {
+		return notFound("selectUser", err, store.User, nickname)
+	}
+	if err := emit(store.ExportProfile, user); err != nil {
+		return err
+	}
+
+	sections := []struct {
+		section   string
+		statement string
+		scan      func(row scanner) (interface{}, error)
+	}{
+		{store.ExportForums, "exportForums", func(row scanner) (interface{}, error) {
+			return scanForum(row)
+		}},
+		{store.ExportThreads, "exportThreads", func(row scanner) (interface{}, error) {
+			return scanThread(row)
+		}},
+		{store.ExportPosts, "exportPosts", func(row scanner) (interface{}, error) {
+			return scanPost(row)
+		}},
+		{store.ExportVotes, "exportVotes", func(row scanner) (interface{}, error) {
+			v := models.ExportVote{}
+			err := row.Scan(&v.Thread, &v.Voice)
+			return v, err
+		}},
+		{store.ExportMemberships, "exportMemberships", func(row scanner) (interface{}, error) {
+			m := models.Membership{}
+			err := row.Scan(&m.Forum, &m.Role)
+			return m, err
+		}},
+	}
+	for _, section := range sections {
+		if err := export(tx, section.statement, user.Nickname, section.scan, func(record interface{}) error {
+			return emit(section.section, record)
+		}); err != nil {
+			return err
+		}
+	}
+	return database.Wrap("commit", tx.Commit())
+}
+
+// export streams the rows of one section straight from the cursor.
+func export(tx *database.Tx, statement, nickname string, scan func(row scanner) (interface{}, error), emit func(record interface{}) error) error {
+	rows, err := tx.Query(statement, nickname)
+	if err != nil {
+		return database.Wrap(statement, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		record, err := scan(rows)
+		if err != nil {
+			return database.Wrap(statement, err)
+		}
+		if err := emit(record); err != nil {
+			return err
+		}
+	}
+	return database.Wrap(statement, rows.Err())
+}
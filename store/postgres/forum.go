@@ -0,0 +1,246 @@
+package postgres
+
+import (
+	"context"
+	"server/database"
+	"server/models"
+	"server/store"
+	"time"
+
+	"github.com/jackc/pgx"
+)
+
+func scanForum(row scanner) (models.Forum, error) {
+	f := models.Forum{}
+	err := row.Scan(&f.Title, &f.User, &f.Slug, &f.Posts, &f.Threads, &f.Parent, &f.Category, &f.Position)
+	return f, err
+}
+
+func (s *Store) CreateForum(ctx context.Context, forum models.Forum) (models.Forum, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return forum, database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("checkUser", forum.User).Scan(&forum.User)
+	if err != nil {
+		return forum, notFound("checkUser", err, store.User, forum.User)
+	}
+
+	// A sub-forum always sits in the category of its parent.
+	if forum.Parent != "" {
+		err = tx.QueryRow("checkParentForum", forum.Parent).Scan(&forum.Parent, &forum.Category)
+		if err != nil {
+			return forum, notFound("checkParentForum", err, store.Forum, forum.Parent)
+		}
+	} else if forum.Category != "" {
+		err = tx.QueryRow("checkCategory", forum.Category).Scan(&forum.Category)
+		if err != nil {
+			return forum, notFound("checkCategory", err, store.Category, forum.Category)
+		}
+	}
+
+	_, err = tx.Exec("insertForum", forum.Title, forum.User, forum.Slug, forum.Parent, forum.Category, forum.Position)
+	if isUniqueViolation(err) {
+		_ = tx.Rollback()
+
+		// A soft-deleted forum keeps its slug but must not be shown.
+		existing, err := scanForum(s.db.QueryRow(ctx, "selectForum", forum.Slug))
+		if err == pgx.ErrNoRows {
+			return models.Forum{}, store.ErrConflict
+		}
+		if err != nil {
+			return forum, database.Wrap("selectForum", err)
+		}
+		return existing, store.ErrConflict
+	}
+	if err != nil {
+		return forum, database.Wrap("insertForum", err)
+	}
+
+	forum.Posts, forum.Threads = 0, 0
+	return forum, database.Wrap("commit", tx.Commit())
+}
+
+func (s *Store) GetForum(ctx context.Context, slug string) (models.Forum, error) {
+	forum, err := scanForum(s.db.QueryRow(ctx, "selectForum", slug))
+	if err != nil {
+		return forum, notFound("selectForum", err, store.Forum, slug)
+	}
+	return forum, nil
+}
+
+func (s *Store) GetDeletedForum(ctx context.Context, slug string) (models.Forum, error) {
+	forum, err := scanForum(s.db.QueryRow(ctx, "selectDeletedForum", slug))
+	if err != nil {
+		return forum, notFound("selectDeletedForum", err, store.Forum, slug)
+	}
+	return forum, nil
+}
+
+func (s *Store) UpdateForum(ctx context.Context, slug string, forum models.Forum) (models.Forum, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return forum, database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	if forum.User != "" {
+		err = tx.QueryRow("checkUser", forum.User).Scan(&forum.User)
+		if err != nil {
+			return forum, notFound("checkUser", err, store.User, forum.User)
+		}
+	}
+
+	result, err := scanForum(tx.QueryRow("updateForum", slug, forum.Title, forum.User))
+	if err != nil {
+		return result, notFound("updateForum", err, store.Forum, slug)
+	}
+
+	// The owner is implied by forum."user", so a role granted before the
+	// transfer would only shadow it.
+	if forum.User != "" {
+		if _, err := tx.Exec("deleteForumRole", result.Slug, result.User); err != nil {
+			return result, database.Wrap("deleteForumRole", err)
+		}
+	}
+
+	return result, database.Wrap("commit", tx.Commit())
+}
+
+func (s *Store) DeleteForum(ctx context.Context, slug string, soft bool) error {
+	if !soft {
+		return s.ClearForum(ctx, slug)
+	}
+
+	tag, err := s.db.Exec(ctx, "softDeleteForum", slug, time.Now())
+	if err != nil {
+		return database.Wrap("softDeleteForum", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return store.NotFound(store.Forum, slug)
+	}
+	return nil
+}
+
+func (s *Store) checkForum(ctx context.Context, slug string) (string, error) {
+	err := s.db.QueryRow(ctx, "checkForum", slug).Scan(&slug)
+	if err != nil {
+		return slug, notFound("checkForum", err, store.Forum, slug)
+	}
+	return slug, nil
+}
+
+func (s *Store) Forums(ctx context.Context, q store.ForumsQuery) ([]models.Forum, error) {
+	if q.Owner != "" {
+		err := s.db.QueryRow(ctx, "checkUser", q.Owner).Scan(&q.Owner)
+		if err != nil {
+			return nil, notFound("checkUser", err, store.User, q.Owner)
+		}
+	}
+
+	statement := "selectForumsBySlug"
+	switch q.Sort {
+	case store.ForumSortTitle:
+		statement = "selectForumsByTitle"
+	case store.ForumSortPosts:
+		statement = "selectForumsByPosts"
+	case store.ForumSortThreads:
+		statement = "selectForumsByThreads"
+	}
+	if q.Desc {
+		statement += "Desc"
+	}
+
+	rows, err := s.db.Query(ctx, statement, q.Limit, q.Owner, q.Since)
+	if err != nil {
+		return nil, database.Wrap(statement, err)
+	}
+	defer rows.Close()
+
+	forums := []models.Forum{}
+	for rows.Next() {
+		f, err := scanForum(rows)
+		if err != nil {
+			return nil, database.Wrap(statement, err)
+		}
+		forums = append(forums, f)
+	}
+
+	return forums, database.Wrap(statement, rows.Err())
+}
+
+func (s *Store) ForumUsers(ctx context.Context, slug string, q store.UsersQuery) ([]models.User, error) {
+	forum, err := s.checkForum(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := "selectUserOrder"
+	if q.Since != "" {
+		statement = "selectUserWhereOrder"
+	}
+	if q.Desc {
+		statement += "Desc"
+	}
+
+	var rows *database.Rows
+	if q.Since == "" {
+		rows, err = s.db.Query(ctx, statement, forum, q.Limit)
+	} else {
+		rows, err = s.db.Query(ctx, statement, forum, q.Limit, q.Since)
+	}
+	if err != nil {
+		return nil, database.Wrap(statement, err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, database.Wrap(statement, err)
+		}
+		users = append(users, u)
+	}
+
+	return users, database.Wrap(statement, rows.Err())
+}
+
+func (s *Store) ForumThreads(ctx context.Context, slug string, q store.ThreadsQuery) ([]models.Thread, error) {
+	forum, err := s.checkForum(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := "selectThreadOrder"
+	if q.Since != nil {
+		statement = "selectThreadWhereOrder"
+	}
+	if q.Desc {
+		statement += "Desc"
+	}
+
+	var rows *database.Rows
+	if q.Since == nil {
+		rows, err = s.db.Query(ctx, statement, forum, q.Limit)
+	} else {
+		rows, err = s.db.Query(ctx, statement, forum, q.Limit, *q.Since)
+	}
+	if err != nil {
+		return nil, database.Wrap(statement, err)
+	}
+	defer rows.Close()
+
+	threads := []models.Thread{}
+	for rows.Next() {
+		t, err := scanThread(rows)
+		if err != nil {
+			return nil, database.Wrap(statement, err)
+		}
+		threads = append(threads, t)
+	}
+
+	return threads, database.Wrap(statement, rows.Err())
+}
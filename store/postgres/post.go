@@ -0,0 +1,304 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"server/database"
+	"server/models"
+	"server/store"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/jackc/pgx"
+)
+
+const postColumns = "id, parent, author, message, isEdited, forum, thread, created, deleted"
+
+func scanPost(row scanner) (models.Post, error) {
+	p := models.Post{}
+	err := row.Scan(&p.Id, &p.Parent, &p.Author, &p.Message, &p.IsEdited, &p.Forum, &p.Thread, &p.Created, &p.IsDeleted)
+	return p, hideAuthor(&p, err)
+}
+
+// hideAuthor blanks the author of a tombstone, which the row keeps for the
+// foreign key.
+func hideAuthor(p *models.Post, err error) error {
+	if p.IsDeleted {
+		p.Author = ""
+	}
+	return err
+}
+
+func (s *Store) CreatePosts(ctx context.Context, ref store.ThreadRef, posts []models.Post) ([]models.Post, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	statement, arg := "selectIdForumThreadBySlug", interface{}(ref.Slug)
+	if ref.IsID() {
+		statement, arg = "selectIdForumThreadById", ref.ID
+	}
+
+	var info models.Thread
+	err = tx.QueryRow(statement, arg).Scan(&info.Id, &info.Forum)
+	if err != nil {
+		return nil, notFound(statement, err, store.Thread, ref.String())
+	}
+
+	if len(posts) == 0 {
+		return []models.Post{}, nil
+	}
+
+	if posts[0].Parent != 0 {
+		var parent int
+		err = tx.QueryRow("selectThreadIdFromPost", posts[0].Parent).Scan(&parent)
+		if err != nil && err != pgx.ErrNoRows {
+			return nil, database.Wrap("selectThreadIdFromPost", err)
+		}
+		if parent != info.Id {
+			return nil, store.ErrParentConflict
+		}
+	}
+
+	create := strfmt.DateTime(time.Now())
+
+	var values string
+	var args []interface{}
+	l := len(posts) - 1
+
+	for i, item := range posts {
+		var author string
+		err = tx.QueryRow("checkUser", item.Author).Scan(&author)
+		if err != nil {
+			return nil, notFound("checkUser", err, store.User, item.Author)
+		}
+
+		values += fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)",
+			i*6+1, i*6+2, i*6+3, i*6+4, i*6+5, i*6+6)
+		args = append(args, item.Parent, item.Author, item.Message, info.Forum, info.Id, create)
+		if i != l {
+			values += ","
+		}
+	}
+
+	query := "INSERT INTO forum.post(parent, author, message, forum, thread, created) VALUES " + values + " RETURNING " + postColumns
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, database.Wrap("insertPosts", err)
+	}
+
+	result := make([]models.Post, 0, len(posts))
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, database.Wrap("insertPosts", err)
+		}
+		result = append(result, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, database.Wrap("insertPosts", err)
+	}
+
+	return result, database.Wrap("commit", tx.Commit())
+}
+
+func (s *Store) GetPost(ctx context.Context, id int) (models.Post, error) {
+	post, err := scanPost(s.db.QueryRow(ctx, "selectPost", id))
+	if err != nil {
+		return post, notFound("selectPost", err, store.Post, fmt.Sprint(id))
+	}
+	return post, nil
+}
+
+func (s *Store) UpdatePost(ctx context.Context, id int, message, editor string) (models.Post, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return models.Post{}, database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	post, err := updatePost(tx, id, message, editor)
+	if err != nil {
+		return post, err
+	}
+	return post, database.Wrap("commit", tx.Commit())
+}
+
+// updatePost keeps the replaced message as a revision when the text changes.
+func updatePost(tx *database.Tx, id int, message, editor string) (models.Post, error) {
+	var previous string
+	err := tx.QueryRow("lockPostMessage", id).Scan(&previous)
+	if err != nil {
+		return models.Post{}, notFound("lockPostMessage", err, store.Post, fmt.Sprint(id))
+	}
+
+	if message != "" && message != previous {
+		_, err = tx.Exec("insertPostRevision", id, editor, previous, time.Now())
+		if err != nil {
+			return models.Post{}, database.Wrap("insertPostRevision", err)
+		}
+	}
+
+	post, err := scanPost(tx.QueryRow("updatePost", message, id))
+	if err != nil {
+		return post, notFound("updatePost", err, store.Post, fmt.Sprint(id))
+	}
+	return post, nil
+}
+
+func (s *Store) PostRevisions(ctx context.Context, id int) ([]models.PostRevision, error) {
+	var post int
+	err := s.db.QueryRow(ctx, "checkPost", id).Scan(&post)
+	if err != nil {
+		return nil, notFound("checkPost", err, store.Post, fmt.Sprint(id))
+	}
+
+	rows, err := s.db.Query(ctx, "selectPostRevisions", id)
+	if err != nil {
+		return nil, database.Wrap("selectPostRevisions", err)
+	}
+	defer rows.Close()
+
+	revisions := []models.PostRevision{}
+	for rows.Next() {
+		r := models.PostRevision{}
+		err := rows.Scan(&r.Id, &r.Post, &r.Editor, &r.Message, &r.Created)
+		if err != nil {
+			return nil, database.Wrap("selectPostRevisions", err)
+		}
+		revisions = append(revisions, r)
+	}
+
+	return revisions, database.Wrap("selectPostRevisions", rows.Err())
+}
+
+func (s *Store) RollbackPost(ctx context.Context, id, revision int, editor string) (models.Post, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return models.Post{}, database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	var message string
+	err = tx.QueryRow("selectPostRevision", id, revision).Scan(&message)
+	if err != nil {
+		return models.Post{}, notFound("selectPostRevision", err, store.Revision, fmt.Sprint(revision))
+	}
+
+	post, err := updatePost(tx, id, message, editor)
+	if err != nil {
+		return post, err
+	}
+	return post, database.Wrap("commit", tx.Commit())
+}
+
+func (s *Store) ThreadPosts(ctx context.Context, ref store.ThreadRef, q store.PostsQuery) ([]models.Post, error) {
+	statement, arg := "selectIdThreadBySlug", interface{}(ref.Slug)
+	if ref.IsID() {
+		statement, arg = "selectIdThreadById", ref.ID
+	}
+
+	var id int
+	err := s.db.QueryRow(ctx, statement, arg).Scan(&id)
+	if err != nil {
+		return nil, notFound(statement, err, store.Thread, ref.String())
+	}
+
+	statement = "flat"
+	switch q.Sort {
+	case "tree":
+		statement = "tree"
+	case "parent_tree":
+		statement = "parentTree"
+	}
+	if q.Desc {
+		statement += "Desc"
+	}
+
+	var rows *database.Rows
+	if q.Since == 0 {
+		rows, err = s.db.Query(ctx, statement, id, q.Limit)
+	} else {
+		statement += "Since"
+		rows, err = s.db.Query(ctx, statement, id, q.Limit, q.Since)
+	}
+	if err != nil {
+		return nil, database.Wrap(statement, err)
+	}
+	defer rows.Close()
+
+	posts := []models.Post{}
+	for rows.Next() {
+		p := models.Post{}
+		err = rows.Scan(
+			&p.Id,
+			&p.Author,
+			&p.Created,
+			&p.Forum,
+			&p.IsEdited,
+			&p.Message,
+			&p.Parent,
+			&p.Thread,
+			&p.IsDeleted)
+		if err = hideAuthor(&p, err); err != nil {
+			return nil, database.Wrap(statement, err)
+		}
+		posts = append(posts, p)
+	}
+
+	return posts, database.Wrap(statement, rows.Err())
+}
+
+func (s *Store) DeletePost(ctx context.Context, id int) (models.Post, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return models.Post{}, database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	post, err := scanPost(tx.QueryRow("lockLivePost", id))
+	if err == nil && post.IsDeleted {
+		err = pgx.ErrNoRows
+	}
+	if err != nil {
+		return post, notFound("lockLivePost", err, store.Post, fmt.Sprint(id))
+	}
+
+	if _, err := tx.Exec("tombstonePost", id, models.DeletedMessage); err != nil {
+		return post, database.Wrap("tombstonePost", err)
+	}
+	// The revisions hold the deleted text too.
+	if _, err := tx.Exec("deletePostRevisions", id); err != nil {
+		return post, database.Wrap("deletePostRevisions", err)
+	}
+	post.Author, post.Message, post.IsEdited, post.IsDeleted = "", models.DeletedMessage, false, true
+	return post, database.Wrap("commit", tx.Commit())
+}
+
+func (s *Store) DeletePostTree(ctx context.Context, id int) (models.Post, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return models.Post{}, database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	post, err := scanPost(tx.QueryRow("lockLivePost", id))
+	if err != nil {
+		return post, notFound("lockLivePost", err, store.Post, fmt.Sprint(id))
+	}
+
+	tag, err := tx.Exec("deletePostTree", post.Thread, id)
+	if err != nil {
+		return post, database.Wrap("deletePostTree", err)
+	}
+	if _, err := tx.Exec("addForumCounters", post.Forum, 0, -int(tag.RowsAffected())); err != nil {
+		return post, database.Wrap("addForumCounters", err)
+	}
+	if _, err := tx.Exec("pruneForumUsers", post.Forum); err != nil {
+		return post, database.Wrap("pruneForumUsers", err)
+	}
+
+	return post, database.Wrap("commit", tx.Commit())
+}
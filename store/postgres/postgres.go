@@ -0,0 +1,37 @@
+package postgres
+
+import (
+	"errors"
+	"server/database"
+	"server/store"
+
+	"github.com/jackc/pgx"
+)
+
+// Store implements store.Store on top of the forum schema and the named
+// statements registered in Prepare.
+type Store struct {
+	db *database.Postgres
+}
+
+var _ store.Store = (*Store)(nil)
+
+func New(db *database.Postgres) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr pgx.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// notFound turns pgx.ErrNoRows into a store.NotFoundError and tags any other
+// error with the statement name.
+func notFound(statement string, err error, what, key string) error {
+	if err == pgx.ErrNoRows {
+		return store.NotFound(what, key)
+	}
+	return database.Wrap(statement, err)
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"server/database"
+	"server/models"
+	"server/store"
+
+	"github.com/jackc/pgx"
+)
+
+func (s *Store) ForumRole(ctx context.Context, forum, nickname string) (string, error) {
+	var role string
+	err := s.db.QueryRow(ctx, "selectForumRole", forum, nickname).Scan(&role)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	return role, database.Wrap("selectForumRole", err)
+}
+
+func (s *Store) ForumRoles(ctx context.Context, slug string) ([]models.ForumRole, error) {
+	forum, err := s.checkForum(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(ctx, "selectForumRoles", forum)
+	if err != nil {
+		return nil, database.Wrap("selectForumRoles", err)
+	}
+	defer rows.Close()
+
+	roles := []models.ForumRole{}
+	for rows.Next() {
+		r := models.ForumRole{}
+		if err := rows.Scan(&r.Forum, &r.Nickname, &r.Role); err != nil {
+			return nil, database.Wrap("selectForumRoles", err)
+		}
+		roles = append(roles, r)
+	}
+
+	return roles, database.Wrap("selectForumRoles", rows.Err())
+}
+
+func (s *Store) SetForumRole(ctx context.Context, role models.ForumRole) (models.ForumRole, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return role, database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("checkForum", role.Forum).Scan(&role.Forum)
+	if err != nil {
+		return role, notFound("checkForum", err, store.Forum, role.Forum)
+	}
+
+	err = tx.QueryRow("checkUser", role.Nickname).Scan(&role.Nickname)
+	if err != nil {
+		return role, notFound("checkUser", err, store.User, role.Nickname)
+	}
+
+	_, err = tx.Exec("upsertForumRole", role.Forum, role.Nickname, role.Role)
+	if err != nil {
+		return role, database.Wrap("upsertForumRole", err)
+	}
+
+	return role, database.Wrap("commit", tx.Commit())
+}
+
+func (s *Store) DeleteForumRole(ctx context.Context, forum, nickname string) (models.ForumRole, error) {
+	role := models.ForumRole{}
+	err := s.db.QueryRow(ctx, "deleteForumRole", forum, nickname).Scan(&role.Forum, &role.Nickname, &role.Role)
+	if err != nil {
+		return role, notFound("deleteForumRole", err, store.Role, nickname)
+	}
+	return role, nil
+}
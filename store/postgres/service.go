@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"server/database"
+	"server/models"
+	"server/store"
+)
+
+func (s *Store) Clear(ctx context.Context) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	for _, statement := range []string{"delForum", "delCategory", "delPost", "delThread", "delUser", "delVote", "delForumUsers", "delForumRole", "delToken", "delAPIKey", "delCredentials"} {
+		if _, err := tx.Exec(statement); err != nil {
+			return database.Wrap(statement, err)
+		}
+	}
+
+	return database.Wrap("commit", tx.Commit())
+}
+
+func (s *Store) ClearForum(ctx context.Context, slug string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("checkForumAny", slug).Scan(&slug)
+	if err != nil {
+		return notFound("checkForumAny", err, store.Forum, slug)
+	}
+
+	for _, statement := range []string{"deleteForumVotes", "deleteForumPosts", "deleteForumThreads", "deleteForumUsers", "deleteForumRoles", "reparentForums", "deleteForum"} {
+		if _, err := tx.Exec(statement, slug); err != nil {
+			return database.Wrap(statement, err)
+		}
+	}
+
+	return database.Wrap("commit", tx.Commit())
+}
+
+func (s *Store) Status(ctx context.Context) (models.Status, error) {
+	var status models.Status
+
+	counts := []struct {
+		statement string
+		dest      *int
+	}{
+		{"countUser", &status.User},
+		{"countForum", &status.Forum},
+		{"countThread", &status.Thread},
+		{"countPost", &status.Post},
+	}
+
+	for _, c := range counts {
+		if err := s.db.QueryRow(ctx, c.statement).Scan(c.dest); err != nil {
+			return status, database.Wrap(c.statement, err)
+		}
+	}
+
+	return status, nil
+}
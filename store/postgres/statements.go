@@ -0,0 +1,215 @@
+package postgres
+
+import "strings"
+
+func (s *Store) Prepare() error {
+	st := s.db.Statements()
+
+	st.Add("insertVote", "INSERT INTO forum.vote(thread, nickname, voice) VALUES ($1, $2, $3)")
+	st.Add("updateVote", "UPDATE forum.vote SET voice = $3 WHERE thread = $1 and nickname = $2")
+	st.Add("selectVote", "SELECT voice FROM forum.vote WHERE thread = $1 and nickname = $2 LIMIT 1")
+
+	st.Add("insertUser", "INSERT INTO forum.\"user\"(nickname, fullname, about, email) VALUES ($1, $2, $3, $4)")
+	st.Add("selectDublicateUser", "SELECT nickname, fullname, about, email FROM forum.\"user\" WHERE nickname = $1 OR email = $2 LIMIT 2")
+	st.Add("selectUser", "SELECT nickname, fullname, about, email FROM forum.\"user\" WHERE nickname = $1 LIMIT 1")
+	st.Add("checkUser", "SELECT nickname FROM forum.\"user\" WHERE nickname = $1 LIMIT 1")
+	st.Add("changeUser", "UPDATE forum.\"user\" \n\t\t\t   SET fullname = COALESCE(NULLIF($1, ''), fullname),\n\t\t\t       about = COALESCE(NULLIF($2, ''), about),\n\t\t\t       email = COALESCE(NULLIF($3, ''), email) \n\t\t\t   WHERE nickname = $4 \n\t\t\t   RETURNING nickname, fullname, about, email")
+	st.Add("selectUserOrderDesc", "select nickname, fullname, about, email\n\t\t\t\t\t\tfrom forum.forum_users\n\t\t\t\t\t\tWHERE forum = $1\n\t\t\t\t\t\torder by nickname desc\n\t\t\t\t\tlimit $2")
+	st.Add("selectUserOrder", "select nickname, fullname, about, email\n\t\t\t\t\t\tfrom forum.forum_users\n\t\t\t\t\t\tWHERE forum = $1\n\t\t\t\t\t\torder by nickname\n\t\t\t\t\tlimit $2")
+	st.Add("selectUserWhereOrderDesc", "select nickname, fullname, about, email\n\t\t\t\t\t\tfrom forum.forum_users\n\t\t\t\t\t\tWHERE forum = $1 and nickname < $3\n\t\t\t\t\t\torder by nickname desc\n\t\t\t\t\tlimit $2")
+	st.Add("selectUserWhereOrder", "select nickname, fullname, about, email\n\t\t\t\t\t\tfrom forum.forum_users\n\t\t\t\t\t\tWHERE forum = $1 and nickname > $3\n\t\t\t\t\t\torder by nickname\n\t\t\t\t\tlimit $2")
+
+	st.Add("ghostForums", "UPDATE forum.forum SET \"user\" = $2 WHERE \"user\" = $1")
+	st.Add("ghostThreads", "UPDATE forum.thread SET author = $2 WHERE author = $1")
+	st.Add("ghostPosts", "UPDATE forum.post SET author = $2 WHERE author = $1")
+	st.Add("ghostRevisions", "UPDATE forum.post_revision SET editor = $2 WHERE editor = $1")
+	st.Add("ghostForumUsers", "UPDATE forum.forum_users SET nickname = $2, fullname = $3, about = $4, email = $5 WHERE nickname = $1")
+	st.Add("subtractUserVotes", "UPDATE forum.thread t SET votes = t.votes - v.voice FROM forum.vote v WHERE v.thread = t.id AND v.nickname = $1")
+	st.Add("deleteUserVotes", "DELETE FROM forum.vote WHERE nickname = $1")
+	st.Add("deleteUserRoles", "DELETE FROM forum.forum_role WHERE nickname = $1")
+	st.Add("deleteUserCredentials", "DELETE FROM forum.credentials WHERE nickname = $1")
+	st.Add("deleteUserTokens", "DELETE FROM forum.token WHERE nickname = $1")
+	st.Add("deleteUserAPIKeys", "DELETE FROM forum.api_key WHERE nickname = $1")
+	st.Add("deleteUser", "DELETE FROM forum.\"user\" WHERE nickname = $1")
+
+	st.Add("exportForums", "SELECT title, \"user\", slug, posts, threads, coalesce(parent, ''), coalesce(category, ''), position FROM forum.forum WHERE \"user\" = $1 ORDER BY slug")
+	st.Add("exportThreads", "SELECT id, title, author, forum, message, votes, coalesce(slug, ''), created FROM forum.thread WHERE author = $1 ORDER BY id")
+	st.Add("exportPosts", "SELECT id, parent, author, message, isEdited, forum, thread, created, deleted FROM forum.post WHERE author = $1 ORDER BY id")
+	st.Add("exportVotes", "SELECT thread, voice FROM forum.vote WHERE nickname = $1 ORDER BY thread")
+	st.Add("exportMemberships", "SELECT fu.forum, coalesce(r.role, '') FROM forum.forum_users fu\n\t\t"+
+		"LEFT JOIN forum.forum_role r ON r.forum = fu.forum AND r.nickname = fu.nickname\n\t\t"+
+		"WHERE fu.nickname = $1 ORDER BY fu.forum")
+
+	// searchUsers: $1 is the escaped LIKE prefix pattern, $5 the lower-cased
+	// query for the trigram match; class 0 are the prefix matches. The
+	// nickname is lower-cased in the default collation rather than its own
+	// POSIX one, which leaves non-ASCII letters alone. The since cursor gets
+	// its class from the users table, so it pages even when the user no
+	// longer matches.
+	st.Add("searchUsers", "WITH candidates AS (\n\t\t"+
+		"SELECT u.nickname, u.fullname, u.about, u.email,\n\t\t"+
+		"CASE WHEN lower(u.nickname::text COLLATE \"default\") LIKE $1 OR lower(u.fullname) LIKE $1 THEN 0 ELSE 1 END AS class\n\t\t"+
+		"FROM forum.\"user\" u\n\t\t"+
+		"WHERE (lower(u.nickname::text COLLATE \"default\") LIKE $1 OR lower(u.fullname) LIKE $1\n\t\t"+
+		"OR lower(u.nickname::text COLLATE \"default\") % $5 OR lower(u.fullname) % $5)\n\t\t"+
+		"AND ($3::citext = '' OR EXISTS (SELECT 1 FROM forum.forum_users fu WHERE fu.forum = $3::citext AND fu.nickname = u.nickname))),\n\t\t"+
+		"since AS (\n\t\t"+
+		"SELECT CASE WHEN lower($4::citext::text COLLATE \"default\") LIKE $1\n\t\t"+
+		"OR EXISTS (SELECT 1 FROM forum.\"user\" u WHERE u.nickname = $4::citext AND lower(u.fullname) LIKE $1) THEN 0 ELSE 1 END AS class)\n\t\t"+
+		"SELECT nickname, fullname, about, email FROM candidates\n\t\t"+
+		"WHERE $4::citext = '' OR (class, nickname) > ((SELECT class FROM since), $4::citext)\n\t\t"+
+		"ORDER BY class, nickname\n\t\tLIMIT $2")
+
+	st.Add("insertForum", "INSERT INTO forum.forum(title, \"user\", slug, parent, category, position)\n\t\t\t   VALUES ($1, $2, $3, nullif($4, ''), nullif($5, ''), $6)")
+	st.Add("selectForum", "SELECT title, \"user\", slug, posts, threads, coalesce(parent, ''), coalesce(category, ''), position FROM forum.forum WHERE slug = $1 AND deleted IS NULL LIMIT 1")
+	st.Add("selectDeletedForum", "SELECT title, \"user\", slug, posts, threads, coalesce(parent, ''), coalesce(category, ''), position FROM forum.forum WHERE slug = $1 AND deleted IS NOT NULL LIMIT 1")
+	st.Add("checkForum", "SELECT slug FROM forum.forum WHERE slug = $1 AND deleted IS NULL LIMIT 1")
+	st.Add("checkForumAny", "SELECT slug FROM forum.forum WHERE slug = $1 LIMIT 1")
+	st.Add("updateForum", "UPDATE forum.forum SET title = COALESCE(NULLIF($2, ''), title), \"user\" = COALESCE(NULLIF($3, ''), \"user\")\n\t\tWHERE slug = $1 AND deleted IS NULL\n\t\tRETURNING title, \"user\", slug, posts, threads, coalesce(parent, ''), coalesce(category, ''), position")
+	st.Add("checkParentForum", "SELECT slug, coalesce(category, '') FROM forum.forum WHERE slug = $1 AND deleted IS NULL LIMIT 1")
+	st.Add("selectForumTree", "SELECT title, \"user\", slug, posts, threads, coalesce(parent, ''), coalesce(category, ''), position FROM forum.forum WHERE deleted IS NULL")
+	st.Add("softDeleteForum", "UPDATE forum.forum SET deleted = $2 WHERE slug = $1 AND deleted IS NULL")
+
+	// selectForumsBy<Sort>[Desc]: $2 filters by owner and $3 is the slug
+	// of the last forum on the previous page, both ignored when empty.
+	for _, column := range []string{"slug", "title", "posts", "threads"} {
+		name := strings.Title(column)
+		for _, order := range []struct{ suffix, cmp, dir string }{{"", ">", ""}, {"Desc", "<", " DESC"}} {
+			st.Add("selectForumsBy"+name+order.suffix, "SELECT f.title, f.\"user\", f.slug, f.posts, f.threads, coalesce(f.parent, ''), coalesce(f.category, ''), f.position FROM forum.forum f\n\t\t"+
+				"WHERE f.deleted IS NULL AND ($2::citext = '' OR f.\"user\" = $2::citext)\n\t\t"+
+				"AND ($3::citext = '' OR (f."+column+", f.slug) "+order.cmp+" (SELECT "+column+", slug FROM forum.forum WHERE slug = $3::citext))\n\t\t"+
+				"ORDER BY f."+column+order.dir+", f.slug"+order.dir+"\n\t\tLIMIT $1")
+		}
+	}
+
+	st.Add("insertCategory", "INSERT INTO forum.category(slug, title, position) VALUES ($1, $2, $3)")
+	st.Add("selectCategory", "SELECT slug, title, position FROM forum.category WHERE slug = $1 LIMIT 1")
+	st.Add("checkCategory", "SELECT slug FROM forum.category WHERE slug = $1 LIMIT 1")
+	st.Add("selectCategories", "SELECT slug, title, position FROM forum.category")
+	st.Add("isSubForum", "WITH RECURSIVE sub AS (\n\t\tSELECT slug FROM forum.forum WHERE slug = $1\n\t\tUNION ALL\n\t\tSELECT f.slug FROM forum.forum f JOIN sub ON f.parent = sub.slug)\n\t\tSELECT EXISTS (SELECT 1 FROM sub WHERE slug = $2)")
+	st.Add("moveForum", "UPDATE forum.forum SET parent = nullif($2, ''), category = nullif($3, ''), position = $4\n\t\tWHERE slug = $1 AND deleted IS NULL\n\t\tRETURNING title, \"user\", slug, posts, threads, coalesce(parent, ''), coalesce(category, ''), position")
+	st.Add("moveSubForums", "WITH RECURSIVE sub AS (\n\t\tSELECT slug FROM forum.forum WHERE parent = $1\n\t\tUNION ALL\n\t\tSELECT f.slug FROM forum.forum f JOIN sub ON f.parent = sub.slug)\n\t\tUPDATE forum.forum SET category = nullif($2, '') WHERE slug IN (SELECT slug FROM sub)")
+
+	st.Add("insertThread", "INSERT INTO forum.thread(title, author, forum, message, votes, slug, created)\n\t\tVALUES ($1, $2, $3, $4, $5, nullif($6, ''), $7)\n\t\tRETURNING id")
+	st.Add("selectThreadById", "SELECT t.id, t.title, t.author, t.forum, t.message, t.votes, coalesce(t.slug, '') as slug, t.created FROM forum.thread t JOIN forum.forum f ON f.slug = t.forum WHERE t.id = $1 AND t.deleted IS NULL AND f.deleted IS NULL LIMIT 1")
+	st.Add("selectThreadOrderDesc", "select t.id, t.title, t.author, t.forum, t.message, t.votes, coalesce(t.slug, '') as slug, t.created\n\t\t\t\t\t\tfrom forum.thread t\n\t\t\t\t\t\twhere t.forum = $1 and t.deleted is null\n\t\t\t\t\t\torder by t.created desc\n\t\t\t\t\t\tlimit $2")
+	st.Add("selectThreadOrder", "select t.id, t.title, t.author, t.forum, t.message, t.votes, coalesce(t.slug, '') as slug, t.created\n\t\t\t\t\t\tfrom forum.thread t\n\t\t\t\t\t\twhere t.forum = $1 and t.deleted is null\n\t\t\t\t\t\torder by t.created\n\t\t\t\t\t\tlimit $2")
+	st.Add("selectThreadWhereOrderDesc", "select t.id, t.title, t.author, t.forum, t.message, t.votes, coalesce(t.slug, '') as slug, t.created\n\t\t\t\t\t\tfrom forum.thread t\n\t\t\t\t\t\twhere t.forum = $1 and t.deleted is null and t.created <= $3\n\t\t\t\t\t\torder by t.created desc\n\t\t\t\t\t\tlimit $2")
+	st.Add("selectThreadWhereOrder", "select t.id, t.title, t.author, t.forum, t.message, t.votes, coalesce(t.slug, '') as slug, t.created\n\t\t\t\t\t\tfrom forum.thread t\n\t\t\t\t\t\twhere t.forum = $1 and t.deleted is null and t.created >= $3\n\t\t\t\t\t\torder by t.created\n\t\t\t\t\t\tlimit $2")
+	// selectUser{Threads,Posts}[Desc]: $1 is the canonical nickname, $3 the
+	// since time or NULL with $5 the id that goes with it, and $4 the forum
+	// or ''.
+	for _, order := range []struct{ suffix, dir, cmp string }{{"", "", ">"}, {"Desc", " DESC", "<"}} {
+		st.Add("selectUserThreads"+order.suffix, "SELECT t.id, t.title, t.author, t.forum, t.message, t.votes, coalesce(t.slug, ''), t.created\n\t\t"+
+			"FROM forum.thread t JOIN forum.forum f ON f.slug = t.forum\n\t\t"+
+			"WHERE t.author = $1 AND t.deleted IS NULL AND f.deleted IS NULL\n\t\t"+
+			"AND ($3::timestamptz IS NULL OR (t.created, t.id) "+order.cmp+" ($3::timestamptz, $5::bigint)) AND ($4::citext = '' OR t.forum = $4::citext)\n\t\t"+
+			"ORDER BY t.created"+order.dir+", t.id"+order.dir+"\n\t\tLIMIT $2")
+		st.Add("selectUserPosts"+order.suffix, "SELECT p.id, p.parent, p.author, p.message, p.isEdited, p.forum, p.thread, p.created, p.deleted\n\t\t"+
+			"FROM forum.post p JOIN forum.thread t ON t.id = p.thread JOIN forum.forum f ON f.slug = p.forum\n\t\t"+
+			"WHERE p.author = $1 AND NOT p.deleted AND t.deleted IS NULL AND f.deleted IS NULL\n\t\t"+
+			"AND ($3::timestamptz IS NULL OR (p.created, p.id) "+order.cmp+" ($3::timestamptz, $5::bigint)) AND ($4::citext = '' OR p.forum = $4::citext)\n\t\t"+
+			"ORDER BY p.created"+order.dir+", p.id"+order.dir+"\n\t\tLIMIT $2")
+	}
+	st.Add("selectIdForumThreadBySlug", "SELECT t.id, t.forum FROM forum.thread t JOIN forum.forum f ON f.slug = t.forum WHERE t.slug = $1 AND t.deleted IS NULL AND f.deleted IS NULL LIMIT 1")
+	st.Add("selectIdForumThreadById", "SELECT t.id, t.forum FROM forum.thread t JOIN forum.forum f ON f.slug = t.forum WHERE t.id = $1 AND t.deleted IS NULL AND f.deleted IS NULL LIMIT 1")
+	st.Add("selectThreadBySlug", "SELECT t.id, t.title, t.author, t.forum, t.message, t.votes, coalesce(t.slug, ''), t.created FROM forum.thread t JOIN forum.forum f ON f.slug = t.forum WHERE t.slug = $1 AND t.deleted IS NULL AND f.deleted IS NULL LIMIT 1")
+	st.Add("updateThreadBySlug", "UPDATE forum.thread t SET title = COALESCE(nullif($1, ''), t.title), message = COALESCE(nullif($2, ''), t.message) FROM forum.forum f\n\t\tWHERE f.slug = t.forum AND t.slug = $3 AND t.deleted IS NULL AND f.deleted IS NULL\n\t\tRETURNING t.id, t.title, t.author, t.forum, t.message, t.votes, coalesce(t.slug, ''), t.created")
+	st.Add("updateThreadById", "UPDATE forum.thread t SET title = COALESCE(nullif($1, ''), t.title), message = COALESCE(nullif($2, ''), t.message) FROM forum.forum f\n\t\tWHERE f.slug = t.forum AND t.id = $3 AND t.deleted IS NULL AND f.deleted IS NULL\n\t\tRETURNING t.id, t.title, t.author, t.forum, t.message, t.votes, coalesce(t.slug, ''), t.created")
+	st.Add("selectThreadStateById", "SELECT id, title, author, forum, message, votes, coalesce(slug, ''), created, deleted IS NOT NULL FROM forum.thread WHERE id = $1 FOR UPDATE")
+	st.Add("selectThreadStateBySlug", "SELECT id, title, author, forum, message, votes, coalesce(slug, ''), created, deleted IS NOT NULL FROM forum.thread WHERE slug = $1 FOR UPDATE")
+	st.Add("selectThreadForumById", "SELECT forum FROM forum.thread WHERE id = $1 LIMIT 1")
+	st.Add("selectThreadForumBySlug", "SELECT forum FROM forum.thread WHERE slug = $1 LIMIT 1")
+	st.Add("setThreadDeleted", "UPDATE forum.thread SET deleted = $2 WHERE id = $1")
+	st.Add("countThreadPosts", "SELECT COUNT(*) FROM forum.post WHERE thread = $1")
+	st.Add("deleteThreadVotes", "DELETE FROM forum.vote WHERE thread = $1")
+	st.Add("deleteThreadPosts", "DELETE FROM forum.post WHERE thread = $1")
+	st.Add("deleteThread", "DELETE FROM forum.thread WHERE id = $1")
+	st.Add("addForumCounters", "UPDATE forum.forum SET threads = threads + $2, posts = posts + $3 WHERE slug = $1")
+	st.Add("pruneForumUsers", "DELETE FROM forum.forum_users fu WHERE fu.forum = $1\n\t\t"+
+		"AND NOT EXISTS (SELECT 1 FROM forum.thread t WHERE t.forum = $1 AND t.author = fu.nickname AND t.deleted IS NULL)\n\t\t"+
+		"AND NOT EXISTS (SELECT 1 FROM forum.post p JOIN forum.thread t ON t.id = p.thread WHERE p.forum = $1 AND p.author = fu.nickname AND NOT p.deleted AND t.deleted IS NULL)")
+	st.Add("restoreForumUsers", "INSERT INTO forum.forum_users(forum, nickname, fullname, about, email)\n\t\t"+
+		"SELECT $1, nickname, fullname, about, email FROM forum.user\n\t\t"+
+		"WHERE nickname IN (SELECT author FROM forum.thread WHERE id = $2 UNION SELECT author FROM forum.post WHERE thread = $2 AND NOT deleted)\n\t\t"+
+		"ON CONFLICT DO NOTHING")
+	st.Add("selectIdThreadById", "SELECT t.id as thread FROM forum.thread t JOIN forum.forum f ON f.slug = t.forum WHERE t.id = $1 AND t.deleted IS NULL AND f.deleted IS NULL LIMIT 1")
+	st.Add("selectIdThreadBySlug", "SELECT t.id as thread FROM forum.thread t JOIN forum.forum f ON f.slug = t.forum WHERE t.slug = $1 AND t.deleted IS NULL AND f.deleted IS NULL LIMIT 1")
+
+	st.Add("selectPost", "SELECT p.id, p.parent, p.author, p.message, p.isEdited, p.forum, p.thread, p.created, p.deleted\n\t\t"+
+		"FROM forum.post p JOIN forum.thread t ON t.id = p.thread JOIN forum.forum f ON f.slug = p.forum\n\t\t"+
+		"WHERE p.id = $1 AND t.deleted IS NULL AND f.deleted IS NULL LIMIT 1")
+	st.Add("updatePost", "UPDATE forum.post\n\t\t\t\tSET message = COALESCE(nullif($1, ''), message), isEdited = CASE $1 WHEN message THEN false WHEN '' THEN false ELSE true end\n\t\t\t\tWHERE id = $2 AND NOT deleted\n\t\t\t\tRETURNING id, parent, author, message, isEdited, forum, thread, created, deleted")
+	st.Add("lockLivePost", "SELECT p.id, p.parent, p.author, p.message, p.isEdited, p.forum, p.thread, p.created, p.deleted\n\t\t"+
+		"FROM forum.post p JOIN forum.thread t ON t.id = p.thread JOIN forum.forum f ON f.slug = p.forum\n\t\t"+
+		"WHERE p.id = $1 AND t.deleted IS NULL AND f.deleted IS NULL FOR UPDATE OF p")
+	st.Add("tombstonePost", "UPDATE forum.post SET message = $2, isEdited = false, deleted = true WHERE id = $1")
+	st.Add("deletePostTree", "DELETE FROM forum.post WHERE thread = $1 AND path @> ARRAY[$2::BIGINT]")
+	st.Add("checkPost", "SELECT p.id FROM forum.post p JOIN forum.thread t ON t.id = p.thread JOIN forum.forum f ON f.slug = p.forum\n\t\t"+
+		"WHERE p.id = $1 AND t.deleted IS NULL AND f.deleted IS NULL LIMIT 1")
+	st.Add("lockPostMessage", "SELECT p.message FROM forum.post p JOIN forum.thread t ON t.id = p.thread JOIN forum.forum f ON f.slug = p.forum\n\t\t"+
+		"WHERE p.id = $1 AND NOT p.deleted AND t.deleted IS NULL AND f.deleted IS NULL FOR UPDATE OF p")
+	st.Add("insertPostRevision", "INSERT INTO forum.post_revision(post, editor, message, created) VALUES ($1, nullif($2, ''), $3, $4)")
+	st.Add("selectPostRevisions", "SELECT id, post, coalesce(editor, ''), message, created FROM forum.post_revision WHERE post = $1 ORDER BY id")
+	st.Add("selectPostRevision", "SELECT message FROM forum.post_revision WHERE post = $1 AND id = $2 LIMIT 1")
+	st.Add("deletePostRevisions", "DELETE FROM forum.post_revision WHERE post = $1")
+	st.Add("selectThreadIdFromPost", "SELECT thread FROM forum.post WHERE id = $1")
+	st.Add("treeDesc", "SELECT id, author, created, forum, isEdited, message, parent, thread, deleted\n\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\tWHERE thread = $1\n\t\t\t\t\t\t\tORDER BY path DESC, id DESC\n\t\t\t\t\t\t\tLIMIT $2")
+	st.Add("tree", "SELECT id, author, created, forum, isEdited, message, parent, thread, deleted\n\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\tWHERE thread = $1\n\t\t\t\t\t\t\tORDER BY path, id\n\t\t\t\t\t\t\tLIMIT $2")
+	st.Add("treeDescSince", "SELECT id, author, created, forum, isEdited, message, parent, thread, deleted\n\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\tWHERE thread = $1 and path < (SELECT path FROM forum.post WHERE id = $3 LIMIT 1)\n\t\t\t\t\t\t\tORDER BY path DESC, id DESC\n\t\t\t\t\t\t\tLIMIT $2")
+	st.Add("treeSince", "SELECT id, author, created, forum, isEdited, message, parent, thread, deleted\n\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\tWHERE thread = $1 and path > (SELECT path FROM forum.post WHERE id = $3 LIMIT 1)\n\t\t\t\t\t\t\tORDER BY path, id\n\t\t\t\t\t\t\tLIMIT $2")
+	st.Add("parentTreeDesc", "SELECT id, author, created, forum, isEdited, message, parent, thread, deleted\n\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\tWHERE path[1] IN (\n\t\t\t\t\t\t\t\tSELECT id\n\t\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\t\tWHERE thread = $1 and parent = 0\n\t\t\t\t\t\t\t\tORDER BY id DESC\n\t\t\t\t\t\t\t\tLIMIT $2)\n\t\t\t\t\t\t\tORDER BY path[1] DESC, path, id")
+	st.Add("parentTree", "SELECT id, author, created, forum, isEdited, message, parent, thread, deleted\n\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\tWHERE path[1] IN (\n\t\t\t\t\t\t\t\tSELECT id\n\t\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\t\tWHERE thread = $1 AND parent = 0\n\t\t\t\t\t\t\t\tORDER BY id\n\t\t\t\t\t\t\t\tLIMIT $2)\n\t\t\t\t\t\t\tORDER BY path")
+	st.Add("parentTreeDescSince", "SELECT id, author, created, forum, isEdited, message, parent, thread, deleted\n\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\tWHERE path[1] IN (\n\t\t\t\t\t\t\t\tSELECT id\n\t\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\t\tWHERE thread = $1 AND parent = 0 and path[1] < (SELECT path[1] FROM forum.post WHERE id = $3 LIMIT 1)\n\t\t\t\t\t\t\t\tORDER BY id DESC\n\t\t\t\t\t\t\t\tLIMIT $2)\n\t\t\t\t\t\t\tORDER BY path[1] DESC, path, id")
+	st.Add("parentTreeSince", "SELECT id, author, created, forum, isEdited, message, parent, thread, deleted\n\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\tWHERE path[1] in (\n\t\t\t\t\t\t\t\tSELECT id\n\t\t\t\t\t\t\t\tFROM forum.post\n\t\t\t\t\t\t\t\tWHERE thread = $1 AND parent = 0 and path[1] > (SELECT path[1] FROM forum.post WHERE id = $3 LIMIT 1)\n\t\t\t\t\t\t\t\tORDER BY id ASC\n\t\t\t\t\t\t\t\tLIMIT $2)\n\t\t\t\t\t\t\tORDER BY path, id")
+	st.Add("flatDesc", "SELECT id, author, created, forum, isEdited, message, parent, thread, deleted\n\t\t\t\t\t   FROM forum.post\n\t\t\t\t\t   WHERE thread = $1\n\t\t\t\t\t   ORDER BY id DESC\n\t\t\t\t\t   LIMIT $2")
+	st.Add("flat", "SELECT id, author, created, forum, isEdited, message, parent, thread, deleted\n\t\t\t\t\t   FROM forum.post\n\t\t\t\t\t   WHERE thread = $1\n\t\t\t\t\t   ORDER BY id\n\t\t\t\t\t   LIMIT $2")
+	st.Add("flatDescSince", "SELECT id, author, created, forum, isEdited, message, parent, thread, deleted\n\t\t\t\t\t   FROM forum.post\n\t\t\t\t\t   WHERE thread = $1 and id < $3\n\t\t\t\t\t   ORDER BY id DESC\n\t\t\t\t\t   LIMIT $2")
+	st.Add("flatSince", "SELECT id, author, created, forum, isEdited, message, parent, thread, deleted\n\t\t\t\t\t   FROM forum.post\n\t\t\t\t\t   WHERE thread = $1 and id > $3\n\t\t\t\t\t   ORDER BY id\n\t\t\t\t\t   LIMIT $2")
+
+	st.Add("selectForumRole", "SELECT role FROM forum.forum_role WHERE forum = $1 AND nickname = $2 LIMIT 1")
+	st.Add("selectForumRoles", "SELECT forum, nickname, role FROM forum.forum_role WHERE forum = $1 ORDER BY nickname")
+	st.Add("upsertForumRole", "INSERT INTO forum.forum_role(forum, nickname, role) VALUES ($1, $2, $3)\n\t\tON CONFLICT (forum, nickname) DO UPDATE SET role = EXCLUDED.role")
+	st.Add("deleteForumRole", "DELETE FROM forum.forum_role WHERE forum = $1 AND nickname = $2 RETURNING forum, nickname, role")
+
+	st.Add("upsertCredentials", "INSERT INTO forum.credentials(nickname, password_hash)\n\t\tSELECT nickname, $2 FROM forum.\"user\" WHERE nickname = $1\n\t\tON CONFLICT (nickname) DO UPDATE SET password_hash = EXCLUDED.password_hash")
+	st.Add("selectCredentials", "SELECT nickname, password_hash FROM forum.credentials WHERE nickname = $1 LIMIT 1")
+	st.Add("insertToken", "INSERT INTO forum.token(hash, nickname, created, expires) VALUES ($1, $2, $3, $4)")
+	st.Add("selectToken", "SELECT hash, nickname, created, expires FROM forum.token WHERE hash = $1 LIMIT 1")
+	st.Add("deleteToken", "DELETE FROM forum.token WHERE hash = $1")
+
+	st.Add("insertAPIKey", "INSERT INTO forum.api_key(hash, nickname, name, scopes, created, expires)\n\t\tVALUES ($1, $2, $3, $4, $5, $6)\n\t\tRETURNING id")
+	st.Add("selectAPIKeys", "SELECT "+apiKeyColumns+" FROM forum.api_key WHERE nickname = $1 ORDER BY id")
+	st.Add("selectAPIKey", "SELECT "+apiKeyColumns+" FROM forum.api_key WHERE hash = $1 LIMIT 1")
+	st.Add("revokeAPIKey", "UPDATE forum.api_key SET revoked = COALESCE(revoked, $3) WHERE nickname = $1 AND id = $2 RETURNING "+apiKeyColumns)
+	st.Add("touchAPIKey", "UPDATE forum.api_key SET last_used = $2 WHERE id = $1")
+
+	st.Add("insertAudit", "INSERT INTO forum.audit_log(created, action, scope, actor, request_id, remote, result)\n\t\tVALUES ($1, $2, $3, $4, $5, $6, $7)")
+	st.Add("selectAudit", "SELECT id, created, action, scope, actor, request_id, remote, result FROM forum.audit_log ORDER BY id DESC LIMIT $1")
+
+	st.Add("deleteForumVotes", "DELETE FROM forum.vote WHERE thread IN (SELECT id FROM forum.thread WHERE forum = $1)")
+	st.Add("deleteForumPosts", "DELETE FROM forum.post WHERE forum = $1")
+	st.Add("deleteForumThreads", "DELETE FROM forum.thread WHERE forum = $1")
+	st.Add("deleteForumUsers", "DELETE FROM forum.forum_users WHERE forum = $1")
+	st.Add("deleteForumRoles", "DELETE FROM forum.forum_role WHERE forum = $1")
+	st.Add("reparentForums", "UPDATE forum.forum SET parent = (SELECT parent FROM forum.forum WHERE slug = $1) WHERE parent = $1")
+	st.Add("deleteForum", "DELETE FROM forum.forum WHERE slug = $1")
+
+	st.Add("delForum", "TRUNCATE forum.forum CASCADE")
+	st.Add("delCategory", "TRUNCATE forum.category CASCADE")
+	st.Add("delPost", "TRUNCATE forum.post CASCADE")
+	st.Add("delThread", "TRUNCATE forum.thread CASCADE")
+	st.Add("delUser", "TRUNCATE forum.\"user\" CASCADE")
+	st.Add("delVote", "TRUNCATE forum.vote CASCADE")
+	st.Add("delForumUsers", "TRUNCATE forum.forum_users CASCADE")
+	st.Add("delForumRole", "TRUNCATE forum.forum_role CASCADE")
+	st.Add("delToken", "TRUNCATE forum.token CASCADE")
+	st.Add("delAPIKey", "TRUNCATE forum.api_key CASCADE")
+	st.Add("delCredentials", "TRUNCATE forum.credentials CASCADE")
+	st.Add("countUser", "SELECT COUNT(*) FROM forum.\"user\"")
+	st.Add("countForum", "SELECT COUNT(*) FROM forum.forum")
+	st.Add("countThread", "SELECT COUNT(*) FROM forum.thread")
+	st.Add("countPost", "SELECT COUNT(*) FROM forum.post")
+
+	return st.Prepare(s.db.GetPostgres())
+}
@@ -0,0 +1,199 @@
+package postgres
+
+import (
+	"context"
+	"server/database"
+	"server/models"
+	"server/store"
+	"time"
+
+	"github.com/jackc/pgx"
+)
+
+func scanThread(row scanner) (models.Thread, error) {
+	t := models.Thread{}
+	err := row.Scan(&t.Id, &t.Title, &t.Author, &t.Forum, &t.Message, &t.Votes, &t.Slug, &t.Created)
+	return t, err
+}
+
+func (s *Store) CreateThread(ctx context.Context, thread models.Thread) (models.Thread, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return thread, database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("checkUser", thread.Author).Scan(&thread.Author)
+	if err != nil {
+		return thread, notFound("checkUser", err, store.User, thread.Author)
+	}
+
+	err = tx.QueryRow("checkForum", thread.Forum).Scan(&thread.Forum)
+	if err != nil {
+		return thread, notFound("checkForum", err, store.Forum, thread.Forum)
+	}
+
+	err = tx.QueryRow("insertThread",
+		thread.Title,
+		thread.Author,
+		thread.Forum,
+		thread.Message,
+		thread.Votes,
+		thread.Slug,
+		thread.Created).Scan(&thread.Id)
+
+	if isUniqueViolation(err) {
+		_ = tx.Rollback()
+
+		// A soft-deleted thread keeps its slug but must not be shown.
+		existing, err := scanThread(s.db.QueryRow(ctx, "selectThreadBySlug", thread.Slug))
+		if err == pgx.ErrNoRows {
+			return models.Thread{}, store.ErrConflict
+		}
+		if err != nil {
+			return thread, database.Wrap("selectThreadBySlug", err)
+		}
+		return existing, store.ErrConflict
+	}
+	if err != nil {
+		return thread, database.Wrap("insertThread", err)
+	}
+
+	return thread, database.Wrap("commit", tx.Commit())
+}
+
+func (s *Store) GetThread(ctx context.Context, ref store.ThreadRef) (models.Thread, error) {
+	statement, arg := "selectThreadBySlug", interface{}(ref.Slug)
+	if ref.IsID() {
+		statement, arg = "selectThreadById", ref.ID
+	}
+
+	thread, err := scanThread(s.db.QueryRow(ctx, statement, arg))
+	if err != nil {
+		return thread, notFound(statement, err, store.Thread, ref.String())
+	}
+	return thread, nil
+}
+
+func (s *Store) UpdateThread(ctx context.Context, ref store.ThreadRef, thread models.Thread) (models.Thread, error) {
+	statement, arg := "updateThreadBySlug", interface{}(ref.Slug)
+	if ref.IsID() {
+		statement, arg = "updateThreadById", ref.ID
+	}
+
+	result, err := scanThread(s.db.QueryRow(ctx, statement, thread.Title, thread.Message, arg))
+	if err != nil {
+		return result, notFound(statement, err, store.Thread, ref.String())
+	}
+	return result, nil
+}
+
+func (s *Store) ThreadForum(ctx context.Context, ref store.ThreadRef) (string, error) {
+	statement, arg := "selectThreadForumBySlug", interface{}(ref.Slug)
+	if ref.IsID() {
+		statement, arg = "selectThreadForumById", ref.ID
+	}
+
+	var forum string
+	err := s.db.QueryRow(ctx, statement, arg).Scan(&forum)
+	if err != nil {
+		return forum, notFound(statement, err, store.Thread, ref.String())
+	}
+	return forum, nil
+}
+
+// lockThread returns the thread, soft-deleted or not, and whether it is
+// deleted, locking its row for the rest of the transaction.
+func lockThread(tx *database.Tx, ref store.ThreadRef) (models.Thread, bool, error) {
+	statement, arg := "selectThreadStateBySlug", interface{}(ref.Slug)
+	if ref.IsID() {
+		statement, arg = "selectThreadStateById", ref.ID
+	}
+
+	t := models.Thread{}
+	var deleted bool
+	err := tx.QueryRow(statement, arg).Scan(&t.Id, &t.Title, &t.Author, &t.Forum, &t.Message, &t.Votes, &t.Slug, &t.Created, &deleted)
+	if err != nil {
+		return t, false, notFound(statement, err, store.Thread, ref.String())
+	}
+	return t, deleted, nil
+}
+
+func (s *Store) DeleteThread(ctx context.Context, ref store.ThreadRef, soft bool) (models.Thread, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return models.Thread{}, database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	thread, deleted, err := lockThread(tx, ref)
+	if err != nil {
+		return thread, err
+	}
+	if soft && deleted {
+		return thread, store.NotFound(store.Thread, ref.String())
+	}
+
+	var posts int
+	if soft {
+		if _, err := tx.Exec("setThreadDeleted", thread.Id, time.Now()); err != nil {
+			return thread, database.Wrap("setThreadDeleted", err)
+		}
+		if err := tx.QueryRow("countThreadPosts", thread.Id).Scan(&posts); err != nil {
+			return thread, database.Wrap("countThreadPosts", err)
+		}
+	} else {
+		if _, err := tx.Exec("deleteThreadVotes", thread.Id); err != nil {
+			return thread, database.Wrap("deleteThreadVotes", err)
+		}
+		tag, err := tx.Exec("deleteThreadPosts", thread.Id)
+		if err != nil {
+			return thread, database.Wrap("deleteThreadPosts", err)
+		}
+		posts = int(tag.RowsAffected())
+		if _, err := tx.Exec("deleteThread", thread.Id); err != nil {
+			return thread, database.Wrap("deleteThread", err)
+		}
+	}
+
+	// A soft-deleted thread already left the counters.
+	if !deleted {
+		if _, err := tx.Exec("addForumCounters", thread.Forum, -1, -posts); err != nil {
+			return thread, database.Wrap("addForumCounters", err)
+		}
+	}
+	if _, err := tx.Exec("pruneForumUsers", thread.Forum); err != nil {
+		return thread, database.Wrap("pruneForumUsers", err)
+	}
+
+	return thread, database.Wrap("commit", tx.Commit())
+}
+
+func (s *Store) RestoreThread(ctx context.Context, ref store.ThreadRef) (models.Thread, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return models.Thread{}, database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	thread, deleted, err := lockThread(tx, ref)
+	if err != nil || !deleted {
+		return thread, err
+	}
+
+	var posts int
+	if _, err := tx.Exec("setThreadDeleted", thread.Id, nil); err != nil {
+		return thread, database.Wrap("setThreadDeleted", err)
+	}
+	if err := tx.QueryRow("countThreadPosts", thread.Id).Scan(&posts); err != nil {
+		return thread, database.Wrap("countThreadPosts", err)
+	}
+	if _, err := tx.Exec("addForumCounters", thread.Forum, 1, posts); err != nil {
+		return thread, database.Wrap("addForumCounters", err)
+	}
+	if _, err := tx.Exec("restoreForumUsers", thread.Forum, thread.Id); err != nil {
+		return thread, database.Wrap("restoreForumUsers", err)
+	}
+
+	return thread, database.Wrap("commit", tx.Commit())
+}
@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"context"
+	"server/database"
+	"server/models"
+	"server/store"
+	"strings"
+)
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row scanner) (models.User, error) {
+	u := models.User{}
+	err := row.Scan(&u.Nickname, &u.Fullname, &u.About, &u.Email)
+	return u, err
+}
+
+func (s *Store) CreateUser(ctx context.Context, user models.User, hash string) ([]models.User, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("insertUser",
+		user.Nickname,
+		user.Fullname,
+		user.About,
+		user.Email)
+
+	if isUniqueViolation(err) {
+		_ = tx.Rollback()
+
+		rows, err := s.db.Query(ctx, "selectDublicateUser", user.Nickname, user.Email)
+		if err != nil {
+			return nil, database.Wrap("selectDublicateUser", err)
+		}
+		defer rows.Close()
+
+		var users []models.User
+		for rows.Next() {
+			u, err := scanUser(rows)
+			if err != nil {
+				return nil, database.Wrap("selectDublicateUser", err)
+			}
+			users = append(users, u)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, database.Wrap("selectDublicateUser", err)
+		}
+
+		return users, store.ErrConflict
+	}
+	if err != nil {
+		return nil, database.Wrap("insertUser", err)
+	}
+
+	if hash != "" {
+		if _, err := tx.Exec("upsertCredentials", user.Nickname, hash); err != nil {
+			return nil, database.Wrap("upsertCredentials", err)
+		}
+	}
+
+	return nil, database.Wrap("commit", tx.Commit())
+}
+
+func (s *Store) GetUser(ctx context.Context, nickname string) (models.User, error) {
+	user, err := scanUser(s.db.QueryRow(ctx, "selectUser", nickname))
+	if err != nil {
+		return user, notFound("selectUser", err, store.User, nickname)
+	}
+	return user, nil
+}
+
+func (s *Store) UpdateUser(ctx context.Context, user models.User) (models.User, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return user, database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	var nickname string
+	err = tx.QueryRow("checkUser", user.Nickname).Scan(&nickname)
+	if err != nil {
+		return user, notFound("checkUser", err, store.User, user.Nickname)
+	}
+
+	result, err := scanUser(tx.QueryRow(
+		"changeUser",
+		user.Fullname,
+		user.About,
+		user.Email,
+		user.Nickname))
+	if isUniqueViolation(err) {
+		return user, store.ErrConflict
+	}
+	if err != nil {
+		return user, database.Wrap("changeUser", err)
+	}
+
+	return result, database.Wrap("commit", tx.Commit())
+}
+
+func (s *Store) SearchUsers(ctx context.Context, q store.UserSearchQuery) ([]models.User, error) {
+	if q.Forum != "" {
+		forum, err := s.checkForum(ctx, q.Forum)
+		if err != nil {
+			return nil, err
+		}
+		q.Forum = forum
+	}
+
+	query := strings.ToLower(q.Query)
+	prefix := likeEscaper.Replace(query) + "%"
+
+	rows, err := s.db.Query(ctx, "searchUsers", prefix, q.Limit, q.Forum, q.Since, query)
+	if err != nil {
+		return nil, database.Wrap("searchUsers", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, database.Wrap("searchUsers", err)
+		}
+		users = append(users, u)
+	}
+
+	return users, database.Wrap("searchUsers", rows.Err())
+}
+
+// likeEscaper makes the search query match literally in a LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (s *Store) AnonymizeUser(ctx context.Context, nickname string, ghost models.User) (models.UserDeletion, error) {
+	result := models.UserDeletion{Ghost: ghost.Nickname}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return result, database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("checkUser", nickname).Scan(&nickname)
+	if err != nil {
+		return result, notFound("checkUser", err, store.User, nickname)
+	}
+
+	_, err = tx.Exec("insertUser", ghost.Nickname, ghost.Fullname, ghost.About, ghost.Email)
+	if err != nil {
+		return result, database.Wrap("insertUser", err)
+	}
+
+	// The votes leave the thread totals before the rows go.
+	if _, err := tx.Exec("subtractUserVotes", nickname); err != nil {
+		return result, database.Wrap("subtractUserVotes", err)
+	}
+
+	tag, err := tx.Exec("deleteUserVotes", nickname)
+	if err != nil {
+		return result, database.Wrap("deleteUserVotes", err)
+	}
+	result.Votes = int(tag.RowsAffected())
+
+	for _, move := range []struct {
+		statement string
+		count     *int
+	}{
+		{"ghostForums", &result.Forums},
+		{"ghostThreads", &result.Threads},
+		{"ghostPosts", &result.Posts},
+		{"ghostRevisions", nil},
+	} {
+		tag, err := tx.Exec(move.statement, nickname, ghost.Nickname)
+		if err != nil {
+			return result, database.Wrap(move.statement, err)
+		}
+		if move.count != nil {
+			*move.count = int(tag.RowsAffected())
+		}
+	}
+
+	_, err = tx.Exec("ghostForumUsers", nickname, ghost.Nickname, ghost.Fullname, ghost.About, ghost.Email)
+	if err != nil {
+		return result, database.Wrap("ghostForumUsers", err)
+	}
+
+	for _, statement := range []string{"deleteUserRoles", "deleteUserCredentials", "deleteUserTokens", "deleteUserAPIKeys", "deleteUser"} {
+		if _, err := tx.Exec(statement, nickname); err != nil {
+			return result, database.Wrap(statement, err)
+		}
+	}
+
+	return result, database.Wrap("commit", tx.Commit())
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"server/database"
+	"server/models"
+	"server/store"
+)
+
+func (s *Store) Vote(ctx context.Context, ref store.ThreadRef, vote models.Vote) (models.Thread, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return models.Thread{}, database.Wrap("begin", err)
+	}
+	defer tx.Rollback()
+
+	var nickname string
+	err = tx.QueryRow("checkUser", vote.Nickname).Scan(&nickname)
+	if err != nil {
+		return models.Thread{}, notFound("checkUser", err, store.User, vote.Nickname)
+	}
+
+	statement, arg := "selectThreadBySlug", interface{}(ref.Slug)
+	if ref.IsID() {
+		statement, arg = "selectThreadById", ref.ID
+	}
+
+	result, err := scanThread(tx.QueryRow(statement, arg))
+	if err != nil {
+		return result, notFound(statement, err, store.Thread, ref.String())
+	}
+
+	vote.Thread = result.Id
+
+	var vot int
+	_, err = tx.Exec("insertVote", vote.Thread, vote.Nickname, vote.Voice)
+	if isUniqueViolation(err) {
+		_ = tx.Rollback()
+		tx, err = s.db.Begin(ctx)
+		if err != nil {
+			return result, database.Wrap("begin", err)
+		}
+		defer tx.Rollback()
+
+		err = tx.QueryRow("selectVote", vote.Thread, vote.Nickname).Scan(&vot)
+		if err != nil {
+			return result, database.Wrap("selectVote", err)
+		}
+		if vot != vote.Voice {
+			_, err = tx.Exec("updateVote", vote.Thread, vote.Nickname, vote.Voice)
+			if err != nil {
+				return result, database.Wrap("updateVote", err)
+			}
+		}
+	} else if err != nil {
+		return result, database.Wrap("insertVote", err)
+	}
+
+	result.Votes = result.Votes - vot + vote.Voice
+
+	return result, database.Wrap("commit", tx.Commit())
+}
@@ -0,0 +1,70 @@
+package store_test
+
+import (
+	"context"
+	"reflect"
+	"server/models"
+	"server/store"
+	"testing"
+)
+
+func nicknames(users []models.User) []string {
+	names := []string{}
+	for _, u := range users {
+		names = append(names, u.Nickname)
+	}
+	return names
+}
+
+func TestSearchUsers(t *testing.T) {
+	eachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		for _, u := range []models.User{
+			{Nickname: "sparrow", Fullname: "Jack Sparrow"},
+			{Nickname: "Sparow.Fan", Fullname: "Fan"},
+			{Nickname: "hector", Fullname: "Sparow Barbossa"},
+			{Nickname: "davy", Fullname: "Davy Jones"},
+			{Nickname: "sparrowhawk", Fullname: "Hawk"},
+		} {
+			u.Email = u.Nickname + "@example.com"
+			if _, err := s.CreateUser(ctx, u, ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		tests := []struct {
+			name  string
+			query store.UserSearchQuery
+			want  []string
+		}{
+			// Prefixes of the nickname or full name come first, then the
+			// trigram matches, each by nickname.
+			{"all", store.UserSearchQuery{Query: "Sparow", Limit: 10}, []string{"hector", "Sparow.Fan", "sparrow", "sparrowhawk"}},
+			{"first page", store.UserSearchQuery{Query: "sparow", Limit: 1}, []string{"hector"}},
+			{"within prefixes", store.UserSearchQuery{Query: "sparow", Since: "hector", Limit: 1}, []string{"Sparow.Fan"}},
+			{"into trigrams", store.UserSearchQuery{Query: "sparow", Since: "sparow.fan", Limit: 1}, []string{"sparrow"}},
+			{"within trigrams", store.UserSearchQuery{Query: "sparow", Since: "sparrow", Limit: 10}, []string{"sparrowhawk"}},
+			{"past the end", store.UserSearchQuery{Query: "sparow", Since: "sparrowhawk", Limit: 10}, []string{}},
+			{"no match", store.UserSearchQuery{Query: "kraken", Limit: 10}, []string{}},
+		}
+		for _, tt := range tests {
+			got, err := s.SearchUsers(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if names := nicknames(got); !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("%s: got %q, want %q", tt.name, names, tt.want)
+			}
+		}
+
+		// The cursor need not be among the matches or be a user at all.
+		got, err := s.SearchUsers(ctx, store.UserSearchQuery{Query: "sparow", Since: "sparow.a", Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"Sparow.Fan", "sparrow", "sparrowhawk"}
+		if names := nicknames(got); !reflect.DeepEqual(names, want) {
+			t.Errorf("foreign cursor: got %q, want %q", names, want)
+		}
+	})
+}
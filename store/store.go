@@ -0,0 +1,316 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"math"
+	"server/models"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrConflict is returned together with the already existing data.
+	ErrConflict = errors.New("store: already exists")
+	// ErrParentConflict means a reply points to a post in another thread.
+	ErrParentConflict = errors.New("store: parent post was created in another thread")
+)
+
+type NotFoundError struct {
+	What string
+	Key  string
+}
+
+func (e *NotFoundError) Error() string {
+	return "store: " + e.What + " not found: " + e.Key
+}
+
+func NotFound(what, key string) error {
+	return &NotFoundError{What: what, Key: key}
+}
+
+// IsNotFound reports whether err says that the given kind of entity is missing.
+func IsNotFound(err error, what string) bool {
+	var nf *NotFoundError
+	return errors.As(err, &nf) && nf.What == what
+}
+
+const (
+	User     = "user"
+	Forum    = "forum"
+	Thread   = "thread"
+	Post     = "post"
+	Token    = "token"
+	APIKey   = "api key"
+	Category = "category"
+	Revision = "revision"
+	Role     = "role"
+)
+
+// ThreadRef is the {slug_or_id} path parameter: a numeric id or a slug.
+type ThreadRef struct {
+	ID   int
+	Slug string
+}
+
+func ParseThreadRef(s string) ThreadRef {
+	if id, err := strconv.Atoi(s); err == nil {
+		return ThreadRef{ID: id}
+	}
+	return ThreadRef{ID: -1, Slug: s}
+}
+
+func (r ThreadRef) IsID() bool {
+	return r.ID != -1
+}
+
+func (r ThreadRef) String() string {
+	if r.IsID() {
+		return strconv.Itoa(r.ID)
+	}
+	return r.Slug
+}
+
+// ForumsQuery pages through forums ordered by Sort, one of the ForumSort
+// values, with the slug breaking ties. Since is the slug of the last forum on
+// the previous page.
+type ForumsQuery struct {
+	Limit int
+	Since string
+	Sort  string
+	Owner string
+	Desc  bool
+}
+
+const (
+	ForumSortSlug    = "slug"
+	ForumSortTitle   = "title"
+	ForumSortPosts   = "posts"
+	ForumSortThreads = "threads"
+)
+
+// The sections of a personal data export, in the order ExportUser emits
+// them: the models.User, the models.Forum the user owns, the models.Thread and
+// models.Post they wrote, their models.ExportVote and their forum
+// models.Membership.
+const (
+	ExportProfile     = "profile"
+	ExportForums      = "forums"
+	ExportThreads     = "threads"
+	ExportPosts       = "posts"
+	ExportVotes       = "votes"
+	ExportMemberships = "memberships"
+)
+
+var ExportSections = []string{ExportProfile, ExportForums, ExportThreads, ExportPosts, ExportVotes, ExportMemberships}
+
+// UserSearchQuery matches nicknames and full names by prefix first and by
+// trigram similarity after that, each group ordered by nickname. Since is
+// the nickname of the last user on the previous page and Forum, when set,
+// keeps the members of that forum.
+type UserSearchQuery struct {
+	Query string
+	Forum string
+	Since string
+	Limit int
+}
+
+type UsersQuery struct {
+	Limit int
+	Since string
+	Desc  bool
+}
+
+type ThreadsQuery struct {
+	Limit int
+	Since *time.Time
+	Desc  bool
+}
+
+// ActivityQuery pages the threads or posts of a user across all forums by
+// (created, id). Since and SinceID are the key of the last item of the
+// previous page; without SinceID, Since is an inclusive time like in
+// ThreadsQuery. Forum, when set, keeps that forum.
+type ActivityQuery struct {
+	Limit   int
+	Since   *time.Time
+	SinceID int
+	Desc    bool
+	Forum   string
+}
+
+// SinceBound is the id that goes with Since in the keyset comparison: SinceID,
+// or the bound that lets in every id created at Since when it is zero.
+func (q ActivityQuery) SinceBound() int {
+	switch {
+	case q.SinceID != 0:
+		return q.SinceID
+	case q.Desc:
+		return math.MaxInt64
+	}
+	return 0
+}
+
+type PostsQuery struct {
+	Limit int
+	Since int
+	Sort  string
+	Desc  bool
+}
+
+type UserStore interface {
+	// CreateUser returns ErrConflict and the users holding the nickname or
+	// email. A non-empty hash is stored as the password with the user.
+	CreateUser(ctx context.Context, user models.User, hash string) ([]models.User, error)
+	GetUser(ctx context.Context, nickname string) (models.User, error)
+	// UpdateUser keeps the fields left empty and returns ErrConflict when the email is taken.
+	UpdateUser(ctx context.Context, user models.User) (models.User, error)
+	SearchUsers(ctx context.Context, q UserSearchQuery) ([]models.User, error)
+	// UserThreads and UserPosts leave out soft-deleted forums and threads
+	// and deleted posts.
+	UserThreads(ctx context.Context, nickname string, q ActivityQuery) ([]models.Thread, error)
+	UserPosts(ctx context.Context, nickname string, q ActivityQuery) ([]models.Post, error)
+	// AnonymizeUser creates the ghost user, hands the forums, threads, posts
+	// and edits of the user over to it, removes the votes from the thread
+	// totals, drops roles, credentials, sessions and API keys, and deletes
+	// the user.
+	AnonymizeUser(ctx context.Context, nickname string, ghost models.User) (models.UserDeletion, error)
+	// ExportUser passes every record of the user to emit, section by section
+	// in the order of ExportSections, without collecting them first. It
+	// returns a NotFoundError before emitting anything when there is no such
+	// user.
+	ExportUser(ctx context.Context, nickname string, emit func(section string, record interface{}) error) error
+}
+
+type ForumStore interface {
+	// CreateForum returns ErrConflict and the existing forum when the slug is
+	// taken, or an empty forum when a soft-deleted one holds it.
+	CreateForum(ctx context.Context, forum models.Forum) (models.Forum, error)
+	GetForum(ctx context.Context, slug string) (models.Forum, error)
+	// GetDeletedForum returns a soft-deleted forum, which GetForum hides.
+	GetDeletedForum(ctx context.Context, slug string) (models.Forum, error)
+	// Forums lists the forums that are not soft-deleted.
+	Forums(ctx context.Context, q ForumsQuery) ([]models.Forum, error)
+	ForumUsers(ctx context.Context, slug string, q UsersQuery) ([]models.User, error)
+	ForumThreads(ctx context.Context, slug string, q ThreadsQuery) ([]models.Thread, error)
+	// UpdateForum changes the title and transfers ownership to forum.User,
+	// keeping the fields left empty.
+	UpdateForum(ctx context.Context, slug string, forum models.Forum) (models.Forum, error)
+	// DeleteForum hides a soft-deleted forum from the API; a hard delete
+	// removes it with everything in it, like ClearForum.
+	DeleteForum(ctx context.Context, slug string, soft bool) error
+}
+
+type ThreadStore interface {
+	// CreateThread returns ErrConflict and the existing thread when the slug
+	// is taken, or an empty thread when a hidden one holds it.
+	CreateThread(ctx context.Context, thread models.Thread) (models.Thread, error)
+	GetThread(ctx context.Context, ref ThreadRef) (models.Thread, error)
+	UpdateThread(ctx context.Context, ref ThreadRef, thread models.Thread) (models.Thread, error)
+	// DeleteThread hides the thread with a soft delete or removes it with its
+	// posts and votes, and returns it as it was. Either way the forum counters
+	// and forum_users stop counting it.
+	DeleteThread(ctx context.Context, ref ThreadRef, soft bool) (models.Thread, error)
+	// ThreadForum returns the forum of the thread, soft-deleted or not.
+	ThreadForum(ctx context.Context, ref ThreadRef) (string, error)
+	// RestoreThread brings a soft-deleted thread back.
+	RestoreThread(ctx context.Context, ref ThreadRef) (models.Thread, error)
+}
+
+type PostStore interface {
+	CreatePosts(ctx context.Context, ref ThreadRef, posts []models.Post) ([]models.Post, error)
+	GetPost(ctx context.Context, id int) (models.Post, error)
+	// UpdatePost keeps the replaced message as a revision by editor, who is
+	// empty when the API runs without authentication.
+	UpdatePost(ctx context.Context, id int, message, editor string) (models.Post, error)
+	// PostRevisions returns the edits of the post, oldest first.
+	PostRevisions(ctx context.Context, id int) ([]models.PostRevision, error)
+	// RollbackPost brings back the message a revision replaced, recorded as
+	// a new edit by editor.
+	RollbackPost(ctx context.Context, id, revision int, editor string) (models.Post, error)
+	ThreadPosts(ctx context.Context, ref ThreadRef, q PostsQuery) ([]models.Post, error)
+	// DeletePost turns the post into a tombstone that keeps its place in the
+	// tree; the forum counters still count it and its author stays among the
+	// forum users.
+	DeletePost(ctx context.Context, id int) (models.Post, error)
+	// DeletePostTree removes the post with all its replies and returns it as
+	// it was.
+	DeletePostTree(ctx context.Context, id int) (models.Post, error)
+}
+
+type VoteStore interface {
+	// Vote adds or changes the user's voice and returns the updated thread.
+	Vote(ctx context.Context, ref ThreadRef, vote models.Vote) (models.Thread, error)
+}
+
+type CategoryStore interface {
+	// CreateCategory returns ErrConflict and the existing category when the
+	// slug is taken.
+	CreateCategory(ctx context.Context, category models.Category) (models.Category, error)
+	// ForumTree nests the forums that are not soft-deleted under their
+	// categories and parents.
+	ForumTree(ctx context.Context) (models.ForumTree, error)
+	// MoveForum reorders the forum or moves it to another parent or
+	// category; its sub-forums follow it into the new category. Moving a
+	// forum under itself or one of its sub-forums returns ErrConflict.
+	MoveForum(ctx context.Context, slug string, move models.ForumMove) (models.Forum, error)
+}
+
+type RoleStore interface {
+	// ForumRole returns the role granted to the user in the forum, or ""
+	// when there is none.
+	ForumRole(ctx context.Context, forum, nickname string) (string, error)
+	ForumRoles(ctx context.Context, forum string) ([]models.ForumRole, error)
+	SetForumRole(ctx context.Context, role models.ForumRole) (models.ForumRole, error)
+	// DeleteForumRole returns the removed role, or NotFound(Role) when the
+	// user had none in the forum.
+	DeleteForumRole(ctx context.Context, forum, nickname string) (models.ForumRole, error)
+}
+
+type AuthStore interface {
+	// PasswordHash returns the registered nickname and its password hash.
+	PasswordHash(ctx context.Context, nickname string) (string, string, error)
+	CreateToken(ctx context.Context, token models.Token) error
+	// GetToken looks a token up by its hash, expired ones included.
+	GetToken(ctx context.Context, hash string) (models.Token, error)
+	DeleteToken(ctx context.Context, hash string) error
+}
+
+type APIKeyStore interface {
+	// CreateAPIKey assigns the id and returns the stored key.
+	CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error)
+	APIKeys(ctx context.Context, nickname string) ([]models.APIKey, error)
+	// GetAPIKey looks a key up by its hash, expired and revoked ones included.
+	GetAPIKey(ctx context.Context, hash string) (models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, nickname string, id int) (models.APIKey, error)
+	TouchAPIKey(ctx context.Context, id int, at time.Time) error
+}
+
+type AuditStore interface {
+	Audit(ctx context.Context, entry models.AuditEntry) error
+	// AuditLog returns the newest entries first.
+	AuditLog(ctx context.Context, limit int) ([]models.AuditEntry, error)
+}
+
+type ServiceStore interface {
+	// Clear deletes everything but the audit log.
+	Clear(ctx context.Context) error
+	// ClearForum deletes one forum with its threads, posts, votes and roles,
+	// soft-deleted or not.
+	ClearForum(ctx context.Context, slug string) error
+	Status(ctx context.Context) (models.Status, error)
+}
+
+type Store interface {
+	UserStore
+	ForumStore
+	CategoryStore
+	ThreadStore
+	PostStore
+	VoteStore
+	RoleStore
+	AuthStore
+	APIKeyStore
+	AuditStore
+	ServiceStore
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"server/models"
+	"sort"
+	"strings"
+)
+
+// BuildForumTree nests the forums under their parents and categories, orders
+// siblings by position and slug and rolls the counters up. Forums whose
+// parent is not in the list are left out with their subtree.
+func BuildForumTree(categories []models.Category, forums []models.Forum) models.ForumTree {
+	children := map[string][]models.Forum{}
+	for _, f := range forums {
+		parent := strings.ToLower(f.Parent)
+		children[parent] = append(children[parent], f)
+	}
+
+	var build func(parent string) []models.ForumNode
+	build = func(parent string) []models.ForumNode {
+		list := children[strings.ToLower(parent)]
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Position != list[j].Position {
+				return list[i].Position < list[j].Position
+			}
+			return strings.ToLower(list[i].Slug) < strings.ToLower(list[j].Slug)
+		})
+
+		nodes := make([]models.ForumNode, 0, len(list))
+		for _, f := range list {
+			node := models.ForumNode{Forum: f, TotalPosts: f.Posts, TotalThreads: f.Threads}
+			node.Children = build(f.Slug)
+			for _, c := range node.Children {
+				node.TotalPosts += c.TotalPosts
+				node.TotalThreads += c.TotalThreads
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+
+	tree := models.ForumTree{Categories: []models.CategoryNode{}, Forums: []models.ForumNode{}}
+	index := map[string]int{}
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].Position != categories[j].Position {
+			return categories[i].Position < categories[j].Position
+		}
+		return strings.ToLower(categories[i].Slug) < strings.ToLower(categories[j].Slug)
+	})
+	for i, c := range categories {
+		index[strings.ToLower(c.Slug)] = i
+		tree.Categories = append(tree.Categories, models.CategoryNode{Category: c, Forums: []models.ForumNode{}})
+	}
+
+	for _, node := range build("") {
+		i, ok := index[strings.ToLower(node.Category)]
+		if !ok {
+			tree.Forums = append(tree.Forums, node)
+			continue
+		}
+		c := &tree.Categories[i]
+		c.Forums = append(c.Forums, node)
+		c.TotalPosts += node.TotalPosts
+		c.TotalThreads += node.TotalThreads
+	}
+	return tree
+}
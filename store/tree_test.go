@@ -0,0 +1,115 @@
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"server/models"
+	"server/store"
+	"server/store/memory"
+	"testing"
+)
+
+// shape flattens a tree to "slug posts/threads" lines, children indented.
+func shape(nodes []models.ForumNode, indent string) []string {
+	lines := []string{}
+	for _, n := range nodes {
+		lines = append(lines, fmt.Sprintf("%s%s %d/%d", indent, n.Slug, n.TotalPosts, n.TotalThreads))
+		lines = append(lines, shape(n.Children, indent+"  ")...)
+	}
+	return lines
+}
+
+func TestBuildForumTree(t *testing.T) {
+	categories := []models.Category{
+		{Slug: "b-cat", Position: 1},
+		{Slug: "a-cat", Position: 2},
+		{Slug: "empty", Position: 3},
+	}
+	forums := []models.Forum{
+		{Slug: "top", Category: "a-cat", Posts: 10, Threads: 1},
+		{Slug: "Sub", Parent: "top", Category: "a-cat", Posts: 5, Threads: 2, Position: 2},
+		{Slug: "sub-a", Parent: "TOP", Category: "a-cat", Posts: 1, Threads: 1, Position: 1},
+		{Slug: "leaf", Parent: "sub", Category: "a-cat", Posts: 7, Threads: 3},
+		{Slug: "other", Category: "b-cat", Posts: 2, Threads: 2},
+		{Slug: "loose", Posts: 4, Threads: 4},
+		// The parent was soft-deleted, so the store left it out.
+		{Slug: "orphan", Parent: "gone", Posts: 100, Threads: 100},
+		{Slug: "orphan-child", Parent: "orphan", Posts: 100, Threads: 100},
+	}
+
+	tree := store.BuildForumTree(categories, forums)
+
+	got := []string{}
+	for _, c := range tree.Categories {
+		got = append(got, fmt.Sprintf("%s %d/%d", c.Slug, c.TotalPosts, c.TotalThreads))
+		got = append(got, shape(c.Forums, "  ")...)
+	}
+	got = append(got, "-")
+	got = append(got, shape(tree.Forums, "  ")...)
+
+	want := []string{
+		"b-cat 2/2",
+		"  other 2/2",
+		"a-cat 23/7",
+		"  top 23/7",
+		"    sub-a 1/1",
+		"    Sub 12/5",
+		"      leaf 7/3",
+		"empty 0/0",
+		"-",
+		"  loose 4/4",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tree:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestForumTreeSkipsSoftDeleted(t *testing.T) {
+	ctx := context.Background()
+	s := memory.New()
+	if _, err := s.CreateUser(ctx, models.User{Nickname: "owner", Email: "owner@example.com"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateCategory(ctx, models.Category{Slug: "cat", Title: "Cat"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []models.Forum{
+		{Slug: "top", Category: "cat"},
+		{Slug: "kept", Parent: "top"},
+		{Slug: "hidden", Parent: "top"},
+		{Slug: "under-hidden", Parent: "hidden"},
+	} {
+		f.Title, f.User = f.Slug, "owner"
+		if _, err := s.CreateForum(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{"kept", "hidden", "under-hidden"} {
+		thread := models.Thread{Forum: f, Author: "owner", Title: f, Message: f}
+		thread, err := s.CreateThread(ctx, thread)
+		if err != nil {
+			t.Fatal(err)
+		}
+		posts := []models.Post{{Author: "owner", Message: "hi"}}
+		if _, err := s.CreatePosts(ctx, store.ThreadRef{ID: thread.Id}, posts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.DeleteForum(ctx, "hidden", true); err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := s.ForumTree(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := shape(tree.Categories[0].Forums, "")
+	want := []string{"top 1/1", "  kept 1/1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tree: got %q, want %q", got, want)
+	}
+	if c := tree.Categories[0]; c.TotalPosts != 1 || c.TotalThreads != 1 {
+		t.Errorf("category totals: got %d/%d, want 1/1", c.TotalPosts, c.TotalThreads)
+	}
+}